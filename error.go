@@ -1,62 +1,153 @@
 package errors
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
 	"regexp"
 	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Err struct {
-	Cause        string     `json:"cause"`
-	Message      string     `json:"message"`
-	StackMessage string     `json:"stack_message"`
-	Trace        ErrTrace   `json:"trace"`
-	Stack        []ErrTrace `json:"stack"`
-	Wrapped      error      `json:"-"`
-	Code         int        `json:"code"`
+	Cause         string                 `json:"cause"`
+	Message       string                 `json:"message"`
+	StackMessage  string                 `json:"stack_message"`
+	Trace         ErrTrace               `json:"trace"`
+	Stack         []ErrTrace             `json:"stack"`
+	Wrapped       error                  `json:"-"`
+	Code          int                    `json:"code"`
+	Reason        string                 `json:"-"`
+	Details       []Detail               `json:"details,omitempty"`
+	HelpURL       string                 `json:"help,omitempty"`
+	Alert         *bool                  `json:"-"`
+	Payload       interface{}            `json:"-"`
+	MaxStack      int                    `json:"-"`
+	TraceID       string                 `json:"trace_id,omitempty"`
+	SpanID        string                 `json:"span_id,omitempty"`
+	Conflict      *ConflictTarget        `json:"conflict_target,omitempty"`
+	Location      string                 `json:"location,omitempty"`
+	Suggestion    string                 `json:"suggestion,omitempty"`
+	RetryAfter    time.Duration          `json:"-"`
+	MaxSize       int64                  `json:"max_size,omitempty"`
+	Allow         []string               `json:"allow,omitempty"`
+	Fields        map[string]interface{} `json:"fields,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	Retryable     *bool                  `json:"-"`
+	PublicMessage string                 `json:"public_message,omitempty"`
+	Op            string                 `json:"op,omitempty"`
+	Ops           []string               `json:"ops,omitempty"`
+	Domain        string                 `json:"domain,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	ID            string                 `json:"error_id,omitempty"`
+	MsgKey        string                 `json:"msg_key,omitempty"`
+	Slug          string                 `json:"slug,omitempty"`
+	Host          *HostInfo              `json:"host,omitempty"`
 }
 
 type ErrTrace struct {
 	File     string `json:"file"`
 	Function string `json:"function"`
 	Line     int    `json:"line"`
+	Package  string `json:"-"`
+	Path     string `json:"-"`
+	Count    int    `json:"count,omitempty"`
 }
 
 //	Error implements the interface
 func (e *Err) Error() string {
 
-	output := fmt.Sprintf("Info: %s", e.Message)
+	message := truncate(e.Message, maxMessageLength)
+
+	output := fmt.Sprintf("Info: %s", message)
 	if e.Trace.Line != 0 {
 
-		cause := e.Cause
-		if len(cause) > 200 {
-			cause = cause[:200] + "..."
-		}
+		cause := truncate(e.Cause, 200)
 
-		output = fmt.Sprintf("Cause: %s | Info: %s | Line: %d | Function: %s", cause, e.Message, e.Trace.Line, e.Trace.Function)
+		if causeFormat == MessageFirst {
+			output = fmt.Sprintf("Info: %s | Cause: %s | Line: %d | Function: %s", message, cause, e.Trace.Line, e.Trace.Function)
+		} else {
+			output = fmt.Sprintf("Cause: %s | Info: %s | Line: %d | Function: %s", cause, message, e.Trace.Line, e.Trace.Function)
+		}
 	}
 
 	return output
 }
 
-func extractErr(err error) Err {
+// Unwrap returns the wrapped error so errors.Is and errors.As can reach it
+func (e *Err) Unwrap() error {
+	return e.Wrapped
+}
+
+// errRef returns a pointer to the Err of a typed error, or nil
+func errRef(err error) *Err {
 
 	switch e := err.(type) {
 	case *Internal:
-		return e.Err
+		return &e.Err
 	case *NotFound:
-		return e.Err
+		return &e.Err
 	case *Conflict:
-		return e.Err
+		return &e.Err
 	case *BadRequest:
-		return e.Err
+		return &e.Err
 	case *Unauthorized:
-		return e.Err
+		return &e.Err
 	case *Fatal:
-		return e.Err
+		return &e.Err
+	case *NoContent:
+		return &e.Err
+	case *PaymentRequired:
+		return &e.Err
+	case *ClientClosedRequest:
+		return &e.Err
+	case *RangeNotSatisfiable:
+		return &e.Err
+	case *UnsupportedMediaType:
+		return &e.Err
+	case *Accepted:
+		return &e.Err
+	case *RequestTimeout:
+		return &e.Err
+	case *TooManyRequests:
+		return &e.Err
+	case *Forbidden:
+		return &e.Err
+	case *Unauthenticated:
+		return &e.Err
+	case *BadGateway:
+		return &e.Err
+	case *PayloadTooLarge:
+		return &e.Err
+	case *MethodNotAllowed:
+		return &e.Err
+	case *Custom:
+		return &e.Err
+	case *TooEarly:
+		return &e.Err
+	case *PreconditionRequired:
+		return &e.Err
+	case *UnavailableForLegalReasons:
+		return &e.Err
 	}
 
+	return nil
+}
+
+// extractErr returns a copy of the Err of a typed error, or an empty Err
+func extractErr(err error) Err {
+	if e := errRef(err); e != nil {
+		return *e
+	}
 	return Err{}
 }
 
@@ -67,28 +158,8 @@ func Stack(err error, trace ErrTrace) error {
 		return err
 	}
 
-	switch e := err.(type) {
-	case *Internal:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *NotFound:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *Conflict:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *BadRequest:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *Unauthorized:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *Fatal:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
-	case *NoContent:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		return e
+	if e := errRef(err); e != nil {
+		e.push(trace)
 	}
 
 	return err
@@ -99,60 +170,262 @@ func StackMsg(err error, msg string, trace ErrTrace) error {
 		return err
 	}
 
-	switch e := err.(type) {
-	case *Internal:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
-	case *NotFound:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
-	case *Conflict:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
-	case *BadRequest:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
-	case *Unauthorized:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
-	case *Fatal:
-		e.Err.Stack = append(e.Err.Stack, trace)
+	if e := errRef(err); e != nil {
+		e.push(trace)
 		e.StackMessage = msg
-		return e
-	case *NoContent:
-		e.Err.Stack = append(e.Err.Stack, trace)
-		e.StackMessage = msg
-		return e
 	}
 	return err
 }
 
-//	ErrorF returns the full error information
+// push appends a trace to the stack unless it already holds as many frames as
+// allowed by MaxStack, or by SetMaxStackDepth when MaxStack is unset. A trace
+// repeating the origin frame right after it is skipped, see SetDedupeOrigin
+func (e *Err) push(trace ErrTrace) {
+	last := len(e.Stack) - 1
+	if dedupeOrigin && last >= 0 && sameFrame(trace, e.Trace) && sameFrame(e.Stack[last], e.Trace) {
+		return
+	}
+	limit := e.MaxStack
+	if limit == 0 {
+		limit = maxStackDepth
+	}
+	if limit > 0 && len(e.Stack) >= limit {
+		return
+	}
+	e.Stack = append(e.Stack, trace)
+}
+
+// Here adds the caller's trace to the stack, short for Stack(err, Trace())
+func Here(err error) error {
+	if err == nil {
+		return err
+	}
+	return Stack(err, callerTrace(3))
+}
+
+// HereMsg adds the caller's trace and a stack message, short for
+// StackMsg(err, msg, Trace())
+func HereMsg(err error, msg string) error {
+	if err == nil {
+		return err
+	}
+	return StackMsg(err, msg, callerTrace(3))
+}
+
+// StackOp adds a trace to the stack like Stack and records op as an operation
+// the error passed through, so GetOps builds up as the error is returned up
+// the call chain. An empty op only adds the trace
+func StackOp(err error, op string, trace ErrTrace) error {
+	if err == nil {
+		return err
+	}
+
+	if e := errRef(err); e != nil {
+		e.push(trace)
+		if op != "" {
+			e.Ops = append(e.Ops, op)
+		}
+	}
+	return err
+}
+
+// HereOp adds the caller's trace and an operation, short for
+// StackOp(err, op, Trace())
+func HereOp(err error, op string) error {
+	if err == nil {
+		return err
+	}
+	return StackOp(err, op, callerTrace(3))
+}
+
+// ReplaceStack overwrites the stack with a copy of frames
+func ReplaceStack(err error, frames []ErrTrace) error {
+	if err == nil {
+		return err
+	}
+
+	if e := errRef(err); e != nil {
+		e.Stack = append([]ErrTrace{}, frames...)
+	}
+	return err
+}
+
+//	ErrorF returns the full error information. Errors that aren't typed only
+//	give their Error text
 func ErrorF(err error) string {
 
 	if err == nil {
 		return ""
 	}
 
-	e := extractErr(err)
-	if e.Cause == "" && e.Message == "" {
+	ref := errRef(err)
+	if ref == nil {
 		return err.Error()
 	}
+	e := *ref
 
 	var stackTrace string
 
-	traceFormat := "> Line=%-4d | Function=%-40s | File=%-30s"
+	head, tail := len(e.Stack), 0
+	if errorFStackLimit > 0 && len(e.Stack) > errorFStackLimit {
+		head = (errorFStackLimit + 1) / 2
+		tail = errorFStackLimit - head
+	}
+	for i, stack := range e.Stack[:head] {
+		stackTrace += "\n" + frameFormatter(i, stack)
+	}
+	if head < len(e.Stack) {
+		stackTrace += fmt.Sprintf("\n... %d frames omitted ...", len(e.Stack)-head-tail)
+		for i := len(e.Stack) - tail; i < len(e.Stack); i++ {
+			stackTrace += "\n" + frameFormatter(i, e.Stack[i])
+		}
+	}
+
+	var reason string
+	if e.Reason != "" {
+		reason = fmt.Sprintf("\n- Reason: %s", e.Reason)
+	}
+
+	var extra string
+	if e.ID != "" {
+		extra += fmt.Sprintf("\n- Error ID: %s", e.ID)
+	}
+	if e.RequestID != "" {
+		extra += fmt.Sprintf("\n- Request ID: %s", e.RequestID)
+	}
+	if e.Suggestion != "" {
+		extra += fmt.Sprintf("\n- Hint: %s", e.Suggestion)
+	}
+	if e.TraceID != "" {
+		extra += fmt.Sprintf("\n- Trace ID: %s", e.TraceID)
+	}
+	if e.SpanID != "" {
+		extra += fmt.Sprintf("\n- Span ID: %s", e.SpanID)
+	}
+	if serviceVersion != "" {
+		extra += fmt.Sprintf("\n- Version: %s", serviceVersion)
+	}
+	if serviceCommit != "" {
+		extra += fmt.Sprintf("\n- Commit: %s", serviceCommit)
+	}
+
+	return fmt.Sprintf("\n Full error information:\n- Cause: %s\n- Info: %s%s\n- Stack msg: %s\n- Error code: %d%s\n- Stack trace: %s", e.Cause, e.Message, reason, e.StackMessage, e.Code, extra, stackTrace)
+}
+
+// Summary returns a one-line description of the error with no trace, like
+// "[404 not_found] user not found". Non-typed errors return err.Error()
+func Summary(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errRef(err) == nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("[%d %s] %s", GetCode(err), kindOf(err), GetMessage(err))
+}
+
+// kindOf returns the snake_case name of the error type, or "" for non-typed errors
+func kindOf(err error) string {
+
+	switch e := err.(type) {
+	case *Internal:
+		return "internal"
+	case *NotFound:
+		return "not_found"
+	case *Conflict:
+		return "conflict"
+	case *BadRequest:
+		return "bad_request"
+	case *Unauthorized:
+		return "unauthorized"
+	case *Fatal:
+		return "fatal"
+	case *NoContent:
+		return "no_content"
+	case *PaymentRequired:
+		return "payment_required"
+	case *ClientClosedRequest:
+		return "client_closed_request"
+	case *RangeNotSatisfiable:
+		return "range_not_satisfiable"
+	case *UnsupportedMediaType:
+		return "unsupported_media_type"
+	case *Accepted:
+		return "accepted"
+	case *RequestTimeout:
+		return "request_timeout"
+	case *TooManyRequests:
+		return "too_many_requests"
+	case *Forbidden:
+		return "forbidden"
+	case *Unauthenticated:
+		return "unauthenticated"
+	case *BadGateway:
+		return "bad_gateway"
+	case *PayloadTooLarge:
+		return "payload_too_large"
+	case *MethodNotAllowed:
+		return "method_not_allowed"
+	case *Custom:
+		return e.Kind
+	case *TooEarly:
+		return "too_early"
+	case *PreconditionRequired:
+		return "precondition_required"
+	case *UnavailableForLegalReasons:
+		return "unavailable_for_legal_reasons"
+	}
+
+	return ""
+}
+
+// Diff compares the kind, code, message and cause of two errors and returns a
+// line per field, marking mismatches with "!". It returns "" when they match
+func Diff(a, b error) string {
+	return diff(a, b, false)
+}
 
-	for _, stack := range e.Stack {
-		stackTrace += fmt.Sprintf("\n"+traceFormat, stack.Line, stack.Function, stack.File)
+// DiffStack works like Diff but also compares the stacks
+func DiffStack(a, b error) string {
+	return diff(a, b, true)
+}
+
+func diff(a, b error, withStack bool) string {
+
+	fields := [][3]string{
+		{"kind", kindOf(a), kindOf(b)},
+		{"code", fmt.Sprint(GetCode(a)), fmt.Sprint(GetCode(b))},
+		{"message", fmt.Sprintf("%q", GetMessage(a)), fmt.Sprintf("%q", GetMessage(b))},
+		{"cause", fmt.Sprintf("%q", GetCause(a)), fmt.Sprintf("%q", GetCause(b))},
+	}
+	if withStack {
+		stackA, stackB := GetStack(a), GetStack(b)
+		for i := 0; i < len(stackA) || i < len(stackB); i++ {
+			var frameA, frameB string
+			if i < len(stackA) {
+				frameA = fmt.Sprintf("%s:%d %s", stackA[i].File, stackA[i].Line, stackA[i].Function)
+			}
+			if i < len(stackB) {
+				frameB = fmt.Sprintf("%s:%d %s", stackB[i].File, stackB[i].Line, stackB[i].Function)
+			}
+			fields = append(fields, [3]string{fmt.Sprintf("stack[%d]", i), frameA, frameB})
+		}
 	}
 
-	return fmt.Sprintf("\n Full error information:\n- Cause: %s\n- Info: %s\n- Stack msg: %s\n- Error code: %d\n- Stack trace: %s", e.Cause, e.Message, e.StackMessage, e.Code, stackTrace)
+	var output string
+	mismatch := false
+	for _, field := range fields {
+		mark, op := " ", "=="
+		if field[1] != field[2] {
+			mark, op = "!", "!="
+			mismatch = true
+		}
+		output += fmt.Sprintf("%s %-10s %s %s %s\n", mark, field[0]+":", field[1], op, field[2])
+	}
+	if !mismatch {
+		return ""
+	}
+	return output
 }
 
 // Unwrap returns the original error
@@ -183,15 +456,58 @@ func GetCause(err error) string {
 	return e.Cause
 }
 
+// AppendCause attaches another underlying error. If the error already wraps one,
+// both are joined so errors.Is finds either of them
+func AppendCause(err error, cause error) error {
+	if err == nil || cause == nil {
+		return err
+	}
+
+	e := errRef(err)
+	if e == nil {
+		return err
+	}
+	if e.Wrapped == nil {
+		e.Wrapped = cause
+		e.Cause = cause.Error()
+		return err
+	}
+	e.Wrapped = stderrors.Join(e.Wrapped, cause)
+	e.Cause += "; " + cause.Error()
+	return err
+}
+
+// CauseError returns the wrapped error of a typed error, or nil if there is none
+func CauseError(err error) error {
+	if e := errRef(err); e != nil {
+		return e.Wrapped
+	}
+	return nil
+}
+
+// GetMessage returns the message of a typed error, falling back to its cause.
+// For other errors it returns err.Error()
 func GetMessage(err error) string {
 	if err == nil {
 		return ""
 	}
-	e := extractErr(err)
-	if e.Message == "" {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok && errRef(err) == nil {
+		var messages []string
+		for _, child := range joined.Unwrap() {
+			if msg := GetMessage(child); msg != "" {
+				messages = append(messages, msg)
+			}
+		}
+		return strings.Join(messages, joinSeparator)
+	}
+	e := errRef(err)
+	if e == nil {
 		return err.Error()
 	}
-	return e.Message
+	if e.Message == "" {
+		return truncate(e.Cause, maxMessageLength)
+	}
+	return truncate(e.Message, maxMessageLength)
 }
 
 func GetTrace(err error) ErrTrace {
@@ -216,6 +532,98 @@ func GetStack(err error) []ErrTrace {
 	return e.Stack
 }
 
+// GetAppStack returns a copy of the stack holding only application frames, as
+// defined by SetAppPrefix
+func GetAppStack(err error) []ErrTrace {
+	if err == nil {
+		return nil
+	}
+
+	stack := []ErrTrace{}
+	for _, trace := range extractErr(err).Stack {
+		if isAppFrame(trace) {
+			stack = append(stack, trace)
+		}
+	}
+	return stack
+}
+
+// GetStackCollapsed returns a copy of the stack with consecutive identical
+// frames merged into one, with Count holding how many times it repeated
+func GetStackCollapsed(err error) []ErrTrace {
+	if err == nil {
+		return nil
+	}
+
+	collapsed := []ErrTrace{}
+	for _, trace := range extractErr(err).Stack {
+		last := len(collapsed) - 1
+		if last >= 0 && sameFrame(collapsed[last], trace) {
+			collapsed[last].Count++
+			continue
+		}
+		trace.Count = 1
+		collapsed = append(collapsed, trace)
+	}
+	return collapsed
+}
+
+func sameFrame(a, b ErrTrace) bool {
+	return a.File == b.File && a.Function == b.Function && a.Line == b.Line
+}
+
+// ErrView is a detached copy of an error's fields, as returned by Snapshot
+type ErrView Err
+
+// Snapshot returns a copy of all the error's fields that doesn't share its
+// slices, so it stays consistent if the error is stacked afterwards
+func Snapshot(err error) ErrView {
+	e := errRef(err)
+	if e == nil {
+		return ErrView{}
+	}
+	view := ErrView(*e)
+	view.Stack = append([]ErrTrace(nil), e.Stack...)
+	view.Details = append([]Detail(nil), e.Details...)
+	view.Allow = append([]string(nil), e.Allow...)
+	view.Tags = append([]string(nil), e.Tags...)
+	if e.Fields != nil {
+		view.Fields = make(map[string]interface{}, len(e.Fields))
+		for key, value := range e.Fields {
+			view.Fields[key] = value
+		}
+	}
+	return view
+}
+
+// RangeStack calls fn for each frame in the stack, stopping when fn returns false.
+// The stack is not copied, so fn must not retain frames beyond the call if the
+// error is being stacked concurrently
+func RangeStack(err error, fn func(i int, t ErrTrace) bool) {
+	if err == nil || fn == nil {
+		return
+	}
+	for i, trace := range extractErr(err).Stack {
+		if !fn(i, trace) {
+			return
+		}
+	}
+}
+
+// StackContainsFunc reports whether any frame in the stack has a function
+// matching name, either as a substring or as a suffix
+func StackContainsFunc(err error, name string) bool {
+	if err == nil || name == "" {
+		return false
+	}
+	for _, trace := range extractErr(err).Stack {
+		if strings.Contains(trace.Function, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func GetStackJson(err error) string {
 	if err == nil {
 		return ""
@@ -245,6 +653,12 @@ func GetCode(err error) int {
 	if err == nil {
 		return 0
 	}
+	if joined := joinedTyped(err); joined != nil {
+		return GetCode(joined)
+	}
+	if sentinelCodes && errRef(err) == nil {
+		return sentinelCode(err)
+	}
 	e := extractErr(err)
 	if e.Code == 0 {
 		if IsNotFound(err) {
@@ -265,15 +679,308 @@ func GetCode(err error) int {
 		if IsNoContent(err) {
 			return 204
 		}
+		if IsPaymentRequired(err) {
+			return 402
+		}
+		if IsClientClosedRequest(err) {
+			return 499
+		}
+		if IsRangeNotSatisfiable(err) {
+			return 416
+		}
+		if IsUnsupportedMediaType(err) {
+			return 415
+		}
+		if IsAccepted(err) {
+			return 202
+		}
+		if IsRequestTimeout(err) {
+			return 408
+		}
+		if IsTooManyRequests(err) {
+			return 429
+		}
+		if IsForbidden(err) {
+			return 403
+		}
+		if IsUnauthenticated(err) {
+			return 401
+		}
+		if IsBadGateway(err) {
+			return 502
+		}
+		if IsPayloadTooLarge(err) {
+			return 413
+		}
+		if IsMethodNotAllowed(err) {
+			return 405
+		}
+		if IsTooEarly(err) {
+			return 425
+		}
+		if IsPreconditionRequired(err) {
+			return 428
+		}
+		if IsUnavailableForLegalReasons(err) {
+			return 451
+		}
 	}
 	return e.Code
 }
 
+// sentinelCode maps well-known standard library errors to a code
+func sentinelCode(err error) int {
+	switch {
+	case stderrors.Is(err, sql.ErrNoRows), stderrors.Is(err, os.ErrNotExist):
+		return 404
+	case stderrors.Is(err, os.ErrPermission):
+		return 403
+	case stderrors.Is(err, context.Canceled):
+		return 499
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return 504
+	}
+	return 0
+}
+
+// maxResponseBody is how much of an upstream body FromResponse keeps as cause
+const maxResponseBody = 512
+
+// FromResponse converts a 4xx or 5xx HTTP response into the matching typed
+// error, using the status text as message and the start of the body, if any,
+// as cause. It returns nil for a nil response and for 1xx, 2xx and 3xx ones,
+// such as 304 Not Modified, which aren't errors.
+// Up to 513 bytes of the body are read and not restored, so don't read the
+// body afterwards; closing it is still up to the caller
+func FromResponse(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < 400 {
+		return nil
+	}
+
+	fields := []interface{}{http.StatusText(resp.StatusCode)}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody+1))
+		cause := string(body)
+		if len(body) > maxResponseBody {
+			cut := maxResponseBody
+			for cut > 0 && !utf8.RuneStart(body[cut]) {
+				cut--
+			}
+			cause = string(body[:cut]) + "..."
+		}
+		if cause != "" {
+			fields = append(fields, stderrors.New(cause))
+		}
+	}
+
+	return NewFromStatus(resp.StatusCode, fields...)
+}
+
+// StackIfNew adds the caller's trace to the stack unless the most recent
+// frame already belongs to the same function
+func StackIfNew(err error) error {
+	if err == nil {
+		return err
+	}
+	trace := callerTrace(3)
+	stack := extractErr(err).Stack
+	if len(stack) > 0 && stack[len(stack)-1].Function == trace.Function {
+		return err
+	}
+	return Stack(err, trace)
+}
+
+// joinedTyped returns the most severe typed error, as ordered by
+// SetCodePriority, among the children of a joined error such as one built by errors.Join.
+// It returns nil if err is typed itself or has no typed children
+func joinedTyped(err error) error {
+	if errRef(err) != nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+
+	var found error
+	for _, child := range joined.Unwrap() {
+		if errRef(child) == nil {
+			child = joinedTyped(child)
+		}
+		if child == nil {
+			continue
+		}
+		if found == nil || codePriority(GetCode(child), GetCode(found)) > 0 {
+			found = child
+		}
+	}
+	return found
+}
+
+// IsTyped reports whether err, or any error it wraps, is one of this
+// package's error types
+func IsTyped(err error) bool {
+	for err != nil {
+		if errRef(err) != nil {
+			return true
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, child := range joined.Unwrap() {
+				if IsTyped(child) {
+					return true
+				}
+			}
+			return false
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return false
+}
+
+// EnsureTyped returns typed errors unchanged and wraps any other error in the
+// type matching fallbackCode, as picked by NewFromStatus, so codes that aren't
+// errors, like 0, give an Internal
+func EnsureTyped(err error, fallbackCode int) error {
+	if err == nil || errRef(err) != nil {
+		return err
+	}
+	return NewFromStatus(fallbackCode, err)
+}
+
+// Downgrade turns a 5xx typed error into the 4xx type matching code, keeping
+// its message, cause and stack. Other errors and codes are returned unchanged
+func Downgrade(err error, code int) error {
+	e := errRef(err)
+	if e == nil || GetCode(err) < 500 || code < 400 || code >= 500 {
+		return err
+	}
+
+	downgraded := NewFromStatus(code)
+	ref := errRef(downgraded)
+	*ref = *e
+	ref.Stack = append([]ErrTrace(nil), e.Stack...)
+	ref.Code = code
+	return downgraded
+}
+
+// Flatten collapses a chain of wrapped errors into a copy of the outermost
+// typed error. Its cause joins the messages of the errors it wraps, newest
+// first, leaving out its own message, and its stack holds the frames of all
+// of them, oldest first. An outer error wrapping nothing keeps its cause.
+// Errors without a typed error in their chain are returned unchanged
+func Flatten(err error) error {
+	outer := err
+	for outer != nil && errRef(outer) == nil {
+		outer = stderrors.Unwrap(outer)
+	}
+	if outer == nil {
+		return err
+	}
+
+	var causes []string
+	stacks := [][]ErrTrace{errRef(outer).Stack}
+	for layer := errRef(outer).Wrapped; layer != nil; {
+		e := errRef(layer)
+		if e == nil {
+			// only the innermost error's text is kept, as outer ones repeat it
+			next := stderrors.Unwrap(layer)
+			if next == nil {
+				causes = append(causes, layer.Error())
+			}
+			layer = next
+			continue
+		}
+		if e.Message != "" {
+			causes = append(causes, e.Message)
+		}
+		stacks = append(stacks, e.Stack)
+		layer = e.Wrapped
+	}
+
+	var stack []ErrTrace
+	for i := len(stacks) - 1; i >= 0; i-- {
+		for _, trace := range stacks[i] {
+			if !containsFrame(stack, trace) {
+				stack = append(stack, trace)
+			}
+		}
+	}
+
+	flat := cloneErr(outer)
+	e := errRef(flat)
+	if len(causes) > 0 {
+		e.Cause = strings.Join(causes, ": ")
+	}
+	e.Stack = stack
+	return flat
+}
+
+func containsFrame(stack []ErrTrace, trace ErrTrace) bool {
+	for _, t := range stack {
+		if sameFrame(t, trace) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneErr returns a shallow copy of a typed error, keeping its concrete type
+func cloneErr(err error) error {
+	v := reflect.ValueOf(err)
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+	return clone.Interface().(error)
+}
+
+// Errors returns the children of a joined error, or err itself as the only
+// element otherwise
+func Errors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// From converts any error into a typed one. Typed errors are returned as is,
+// context cancellation maps to ClientClosedRequest (499), an exceeded deadline
+// to a 504 Internal and anything else to Internal. The original error is kept
+// as the cause
+func From(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errRef(err) != nil {
+		return err
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return NewClientClosedRequest(err)
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return NewInternal(err, 504)
+	}
+	return NewInternal(err)
+}
+
 func Trace() ErrTrace {
+	return callerTrace(3)
+}
+
+// callerTrace builds the trace of the frame skip levels above runtime.Callers,
+// so 3 is the caller of the exported function that called it
+func callerTrace(skip int) ErrTrace {
 	pc := make([]uintptr, 10)
-	runtime.Callers(2, pc)
-	function := runtime.FuncForPC(pc[0])
-	file, line := function.FileLine(pc[0])
+	n := runtime.Callers(skip, pc)
+	if n == 0 {
+		return ErrTrace{}
+	}
+
+	// CallersFrames resolves inlined calls, which FuncForPC attributes to the
+	// function they were inlined into
+	frame, _ := runtime.CallersFrames(pc[:n]).Next()
+	file, line := frame.File, frame.Line
 
 	// matching only the file name
 	rgx, err := regexp.Compile(`(?i)/([\w\d_+*()\[\]%=\-]+\.\w+)$`)
@@ -284,13 +991,22 @@ func Trace() ErrTrace {
 		}
 	}
 
-	funcName := function.Name()
+	funcName := frame.Function
 	rgx, err = regexp.Compile(`(?i)(/[\w\d_*().\-]+$)`)
-	if err == nil {
+	if err == nil && !fullFunctionNames {
 		matches := rgx.FindStringSubmatch(funcName)
 		if len(matches) > 0 {
 			funcName = matches[1]
 		}
 	}
-	return ErrTrace{Line: line, File: file, Function: funcName}
+	return ErrTrace{Line: line, File: file, Function: funcName, Package: packageOf(frame.Function), Path: frame.File}
+}
+
+// packageOf returns the import path of a full function name as reported by runtime
+func packageOf(funcName string) string {
+	slash := strings.LastIndex(funcName, "/")
+	if dot := strings.Index(funcName[slash+1:], "."); dot >= 0 {
+		return funcName[:slash+1+dot]
+	}
+	return funcName
 }
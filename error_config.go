@@ -0,0 +1,275 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// CauseFormat sets the order of the cause and the message in Error
+type CauseFormat int
+
+const (
+	CauseFirst CauseFormat = iota
+	MessageFirst
+)
+
+var (
+	causeFormat      = CauseFirst
+	maxMessageLength int
+	maxFormatLength  int
+	maxStackDepth    int
+	errorFStackLimit int
+	frameFormatter   = defaultFrameFormatter
+	joinSeparator    = "; "
+	codePriority     = defaultCodePriority
+	serviceVersion   string
+	serviceCommit    string
+
+	fullFunctionNames bool
+	sentinelCodes     bool
+	valueEquality     bool
+	dedupeOrigin      = true
+	captureHost       bool
+
+	hostPodVar = "POD_NAME"
+	hostEnvVar = "APP_ENV"
+
+	appPrefix   string
+	appPrefixMu sync.RWMutex
+
+	predicates   = map[string]func(error) bool{}
+	predicatesMu sync.RWMutex
+
+	// selfPackage is the import path of this package, wherever it is vendored
+	selfPackage = reflect.TypeOf(Err{}).PkgPath()
+)
+
+func defaultFrameFormatter(i int, t ErrTrace) string {
+	return fmt.Sprintf("> Line=%-4d | Function=%-40s | File=%-30s", t.Line, t.Function, t.File)
+}
+
+// SetMaxFormatArgLength truncates messages built by the formatted constructors,
+// such as NotFoundf, to n characters followed by "...". 0, the default, keeps
+// them whole
+func SetMaxFormatArgLength(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxFormatLength = n
+}
+
+// SetMaxStackDepth caps how many frames Stack and StackMsg keep on an error,
+// ignoring further frames. 0 means unlimited, which is the default.
+// WithMaxStack overrides it per error
+func SetMaxStackDepth(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxStackDepth = n
+}
+
+// SetJoinSeparator sets the separator GetMessage puts between the messages of
+// a joined error's children. The default is "; "
+func SetJoinSeparator(sep string) {
+	joinSeparator = sep
+}
+
+// SetServiceVersion sets the version of the running service, printed by ErrorF
+// and included in the JSON output. Call it once at startup
+func SetServiceVersion(version string) {
+	serviceVersion = version
+}
+
+// GetServiceVersion returns the version set with SetServiceVersion
+func GetServiceVersion() string {
+	return serviceVersion
+}
+
+// SetBuildInfo works like SetServiceVersion and also sets the commit the
+// binary was built from, printed next to the version
+func SetBuildInfo(version, commit string) {
+	serviceVersion = version
+	serviceCommit = commit
+}
+
+// GetServiceCommit returns the commit set with SetBuildInfo
+func GetServiceCommit() string {
+	return serviceCommit
+}
+
+// SetErrorFStackLimit makes ErrorF print only the first and last frames of
+// stacks longer than n, noting how many were omitted. 0 means no limit
+func SetErrorFStackLimit(n int) {
+	if n < 0 {
+		n = 0
+	}
+	errorFStackLimit = n
+}
+
+func defaultCodePriority(a, b int) int {
+	return a - b
+}
+
+// SetCodePriority sets how GetCode picks the code of a joined error: fn returns
+// a positive number when code a is more severe than b, a negative one when it
+// is less severe and 0 when they are equal. By default the highest code wins.
+// Passing nil restores the default
+func SetCodePriority(fn func(a, b int) int) {
+	if fn == nil {
+		fn = defaultCodePriority
+	}
+	codePriority = fn
+}
+
+// EditorLinks returns a frame formatter, to use with SetFrameFormatter, that
+// renders each frame as a scheme://path:line link, e.g. EditorLinks("vscode")
+// or EditorLinks("file"). Frames without a full path link to the file name
+func EditorLinks(scheme string) func(i int, t ErrTrace) string {
+	return func(i int, t ErrTrace) string {
+		path := t.Path
+		if path == "" {
+			path = t.File
+		}
+		link := fmt.Sprintf("%s://%s:%d", scheme, path, t.Line)
+		if scheme == "vscode" {
+			link = fmt.Sprintf("vscode://file%s:%d", path, t.Line)
+		}
+		return fmt.Sprintf("> %s | Function=%s", link, t.Function)
+	}
+}
+
+// SetFrameFormatter changes how each stack frame is rendered by ErrorF.
+// Passing nil restores the default format
+func SetFrameFormatter(fn func(i int, t ErrTrace) string) {
+	if fn == nil {
+		fn = defaultFrameFormatter
+	}
+	frameFormatter = fn
+}
+
+// SetCauseFormat sets whether Error prints the cause or the message first.
+// The default is CauseFirst. Errors without a trace only print the message
+func SetCauseFormat(format CauseFormat) {
+	causeFormat = format
+}
+
+// SetMaxMessageLength caps the message rendered by Error and GetMessage,
+// adding "..." to truncated messages. 0 means unlimited, which is the default
+func SetMaxMessageLength(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxMessageLength = n
+}
+
+// SetFullFunctionNames makes Trace record the package-qualified function name
+// (e.g. github.com/acme/svc.(*Service).Method) instead of the short form
+func SetFullFunctionNames(full bool) {
+	fullFunctionNames = full
+}
+
+// SetSentinelCodes makes GetCode map well-known standard library errors that
+// aren't typed, such as sql.ErrNoRows or os.ErrNotExist (404) and
+// os.ErrPermission (403), instead of returning 0
+func SetSentinelCodes(enabled bool) {
+	sentinelCodes = enabled
+}
+
+// SetDedupeOrigin controls whether stacking the same frame the error was
+// created with, right after creation, is skipped. It is enabled by default
+func SetDedupeOrigin(enabled bool) {
+	dedupeOrigin = enabled
+}
+
+// SetValueEquality makes errors.Is treat two typed errors of the same kind,
+// code and message as equal, ignoring trace and stack. It is off by default,
+// so errors.Is only matches the same error value
+func SetValueEquality(enabled bool) {
+	valueEquality = enabled
+}
+
+// SetCaptureHost makes every constructed error record the host it was created
+// on, as the CaptureHost option does. It is off by default
+func SetCaptureHost(enabled bool) {
+	captureHost = enabled
+}
+
+// SetHostEnvVars sets the environment variables the pod name and the
+// environment are read from when capturing the host. The defaults are
+// POD_NAME and APP_ENV
+func SetHostEnvVars(pod, env string) {
+	hostPodVar = pod
+	hostEnvVar = env
+}
+
+// SetAppPrefix sets the package prefix of the application code (e.g.
+// "github.com/acme/"), used to tell application frames apart from others
+func SetAppPrefix(prefix string) {
+	appPrefixMu.Lock()
+	defer appPrefixMu.Unlock()
+	appPrefix = prefix
+}
+
+// AppPrefix returns the prefix set with SetAppPrefix
+func AppPrefix() string {
+	appPrefixMu.RLock()
+	defer appPrefixMu.RUnlock()
+	return appPrefix
+}
+
+// RegisterPredicate registers a named detection function to be used with
+// Matches, e.g. to recognize a third-party error. Registering an existing name
+// replaces it
+func RegisterPredicate(name string, fn func(error) bool) {
+	predicatesMu.Lock()
+	defer predicatesMu.Unlock()
+	if fn == nil {
+		delete(predicates, name)
+		return
+	}
+	predicates[name] = fn
+}
+
+// Matches reports whether err satisfies the predicate registered under name.
+// Unknown names never match
+func Matches(err error, name string) bool {
+	if err == nil {
+		return false
+	}
+	predicatesMu.RLock()
+	fn, ok := predicates[name]
+	predicatesMu.RUnlock()
+	return ok && fn(err)
+}
+
+// isAppFrame reports whether the frame belongs to the application. Without an
+// app prefix, any frame outside this package and the standard library counts
+func isAppFrame(t ErrTrace) bool {
+	if prefix := AppPrefix(); prefix != "" {
+		return strings.HasPrefix(t.Package, prefix)
+	}
+	if t.Package == "" || t.Package == selfPackage {
+		return false
+	}
+	// standard library paths have no dot in their first element
+	first := strings.SplitN(t.Package, "/", 2)[0]
+	return strings.Contains(first, ".") || t.Package == "main"
+}
+
+// truncate shortens s to n characters followed by "...", cutting between
+// runes so multibyte text stays valid UTF-8. n <= 0 keeps s whole
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i] + "..."
+		}
+		count++
+	}
+	return s
+}
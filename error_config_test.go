@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMaxMessageLength(t *testing.T) {
+	SetMaxMessageLength(4)
+	t.Cleanup(func() { SetMaxMessageLength(0) })
+
+	err := NewNotFound("abcdefghij")
+	if got := GetMessage(err); got != "abcd..." {
+		t.Errorf("GetMessage = %q, want %q", got, "abcd...")
+	}
+	if got := err.Error(); got != "Info: abcd..." {
+		t.Errorf("Error = %q, want %q", got, "Info: abcd...")
+	}
+	if got := GetMessage(NewNotFound("abcd")); got != "abcd" {
+		t.Errorf("GetMessage at the limit = %q, want it whole", got)
+	}
+}
+
+func TestMaxMessageLengthMultibyte(t *testing.T) {
+	SetMaxMessageLength(1)
+	t.Cleanup(func() { SetMaxMessageLength(0) })
+
+	got := GetMessage(NewNotFound("ñandú"))
+	if got != "ñ..." {
+		t.Errorf("GetMessage = %q, want %q", got, "ñ...")
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("GetMessage = %q is not valid UTF-8", got)
+	}
+}
+
+func TestMaxFormatArgLengthMultibyte(t *testing.T) {
+	SetMaxFormatArgLength(3)
+	t.Cleanup(func() { SetMaxFormatArgLength(0) })
+
+	got := GetMessage(NotFoundf("user %s", "ñandú"))
+	if got != "use..." {
+		t.Errorf("GetMessage = %q, want %q", got, "use...")
+	}
+	got = GetMessage(NotFoundf("%s", "ñandú"))
+	if got != "ñan..." || !utf8.ValidString(got) {
+		t.Errorf("GetMessage = %q, want %q", got, "ñan...")
+	}
+}
+
+func TestMaxFormatArgLengthLargeValue(t *testing.T) {
+	SetMaxFormatArgLength(32)
+	t.Cleanup(func() { SetMaxFormatArgLength(0) })
+
+	type request struct {
+		Headers map[string]string
+		Body    []byte
+	}
+	big := request{Headers: map[string]string{"Authorization": "Bearer x"}, Body: make([]byte, 10000)}
+
+	got := GetMessage(NotFoundf("request %+v", big))
+	if utf8.RuneCountInString(got) != 35 || !strings.HasPrefix(got, "request {Headers:map[") || !strings.HasSuffix(got, "...") {
+		t.Errorf("GetMessage = %q, want 32 characters followed by ...", got)
+	}
+	if got := GetMessage(NotFoundf("user %d", 7)); got != "user 7" {
+		t.Errorf("GetMessage under the limit = %q, want it whole", got)
+	}
+}
+
+func TestErrorCauseTruncationMultibyte(t *testing.T) {
+	err := NewInternal("failed", stderrors.New(strings.Repeat("é", 300)), Trace())
+	if msg := err.Error(); !utf8.ValidString(msg) || !strings.Contains(msg, strings.Repeat("é", 200)+"...") {
+		t.Errorf("Error = %q, want the cause cut after 200 characters", msg)
+	}
+}
+
+func TestSetBuildInfo(t *testing.T) {
+	SetBuildInfo("v1.2.3", "abc123")
+	t.Cleanup(func() { SetBuildInfo("", "") })
+
+	output := ErrorF(NewInternal("x"))
+	if !strings.Contains(output, "- Version: v1.2.3") || !strings.Contains(output, "- Commit: abc123") {
+		t.Errorf("ErrorF has no version or commit:\n%s", output)
+	}
+	if strings.Count(output, "v1.2.3") != 1 {
+		t.Errorf("ErrorF prints the version more than once:\n%s", output)
+	}
+
+	var decoded errJSON
+	if e := json.Unmarshal(GetJSON(NewInternal("x")), &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded.Version != "v1.2.3" || decoded.Commit != "abc123" {
+		t.Errorf("JSON version %q and commit %q", decoded.Version, decoded.Commit)
+	}
+}
+
+func TestSetCauseFormat(t *testing.T) {
+	t.Cleanup(func() { SetCauseFormat(CauseFirst) })
+
+	trace := ErrTrace{File: "svc.go", Function: "Load", Line: 12}
+	tests := []struct {
+		name   string
+		format CauseFormat
+		err    error
+		want   string
+	}{
+		{"cause first", CauseFirst, NewInternal("m", stderrors.New("c"), trace), "Cause: c | Info: m | Line: 12 | Function: Load"},
+		{"message first", MessageFirst, NewInternal("m", stderrors.New("c"), trace), "Info: m | Cause: c | Line: 12 | Function: Load"},
+		{"cause first without a trace", CauseFirst, NewInternal("m", stderrors.New("c")), "Info: m"},
+		{"message first without a trace", MessageFirst, NewInternal("m", stderrors.New("c")), "Info: m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetCauseFormat(tt.format)
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxMessageLengthCauseOnly(t *testing.T) {
+	SetMaxMessageLength(4)
+	t.Cleanup(func() { SetMaxMessageLength(0) })
+
+	if got := GetMessage(NewNotFound(stderrors.New("sql: no rows"))); got != "sql:..." {
+		t.Errorf("GetMessage = %q, want the cause cut to %q", got, "sql:...")
+	}
+}
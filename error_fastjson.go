@@ -0,0 +1,114 @@
+package errors
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// FastJSON encodes the code, message, cause and stack of the error without
+// reflection, for hot logging paths. It produces the same keys as GetJSON but
+// leaves out everything else, so use GetJSON for the complete error
+func FastJSON(err error) []byte {
+	if err == nil {
+		return []byte("null")
+	}
+
+	j := toErrJSON(err)
+	buf := make([]byte, 0, 128+len(j.Message)+len(j.Cause)+64*len(j.Stack))
+
+	buf = append(buf, '{')
+	if j.Code != 0 {
+		buf = appendJSONKey(buf, "code")
+		buf = strconv.AppendInt(buf, int64(j.Code), 10)
+		buf = append(buf, ',')
+	}
+	buf = appendJSONKey(buf, "message")
+	buf = appendJSONString(buf, j.Message)
+	if j.Cause != "" {
+		buf = append(buf, ',')
+		buf = appendJSONKey(buf, "cause")
+		buf = appendJSONString(buf, j.Cause)
+	}
+	if len(j.Stack) > 0 {
+		buf = append(buf, ',')
+		buf = appendJSONKey(buf, "stack")
+		buf = append(buf, '[')
+		for i, trace := range j.Stack {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, `{"file":`...)
+			buf = appendJSONString(buf, trace.File)
+			buf = append(buf, `,"function":`...)
+			buf = appendJSONString(buf, trace.Function)
+			buf = append(buf, `,"line":`...)
+			buf = strconv.AppendInt(buf, int64(trace.Line), 10)
+			if trace.Count != 0 {
+				buf = append(buf, `,"count":`...)
+				buf = strconv.AppendInt(buf, int64(trace.Count), 10)
+			}
+			buf = append(buf, '}')
+		}
+		buf = append(buf, ']')
+	}
+	return append(buf, '}')
+}
+
+// appendJSONKey appends the key, renamed as set with SetJSONFieldNames, and
+// its colon
+func appendJSONKey(buf []byte, key string) []byte {
+	buf = appendJSONString(buf, jsonKey(key))
+	return append(buf, ':')
+}
+
+// appendJSONString appends s as a quoted JSON string, escaping it the way
+// encoding/json does
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
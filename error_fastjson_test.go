@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+)
+
+// fastJSONShape is what FastJSON encodes, for comparing it with encoding/json
+type fastJSONShape struct {
+	Code    int        `json:"code,omitempty"`
+	Message string     `json:"message"`
+	Cause   string     `json:"cause,omitempty"`
+	Stack   []ErrTrace `json:"stack,omitempty"`
+}
+
+func TestFastJSONMatchesEncodingJSON(t *testing.T) {
+	tricky := "quote\" backslash\\ tab\t newline\n nul\x00 <b>&amp;</b> \u2028\u2029 ñandú 😀 invalid\xff\xfe end"
+
+	err := NewNotFound(tricky, stderrors.New(tricky), ErrTrace{File: "a.go", Function: "f", Line: 3})
+	Stack(err, ErrTrace{File: "b.go", Function: tricky, Line: 7, Count: 2})
+
+	tests := []error{
+		err,
+		NewInternal(),
+		stderrors.New(tricky),
+	}
+	for _, e := range tests {
+		j := toErrJSON(e)
+		want, marshalErr := json.Marshal(fastJSONShape{Code: j.Code, Message: j.Message, Cause: j.Cause, Stack: j.Stack})
+		if marshalErr != nil {
+			t.Fatal(marshalErr)
+		}
+		if got := FastJSON(e); string(got) != string(want) {
+			t.Errorf("FastJSON =\n%s\nwant\n%s", got, want)
+		}
+	}
+
+	if got := string(FastJSON(nil)); got != "null" {
+		t.Errorf("FastJSON(nil) = %s, want null", got)
+	}
+}
+
+func TestFastJSONFieldNames(t *testing.T) {
+	if e := SetJSONFieldNames(map[string]string{"message": "error", "code": "status"}); e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = SetJSONFieldNames(nil) })
+
+	got := string(FastJSON(NewNotFound("missing")))
+	if want := `{"status":404,"error":"missing"}`; got != want {
+		t.Errorf("FastJSON = %s, want %s", got, want)
+	}
+}
+
+func benchmarkError() error {
+	err := NewNotFound("user not found", stderrors.New("sql: no rows in result set"), Trace())
+	for i := 0; i < 5; i++ {
+		Stack(err, ErrTrace{File: "service.go", Function: "(*Service).Load", Line: 40 + i})
+	}
+	return err
+}
+
+func BenchmarkFastJSON(b *testing.B) {
+	err := benchmarkError()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		FastJSON(err)
+	}
+}
+
+func BenchmarkGetJSON(b *testing.B) {
+	err := benchmarkError()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetJSON(err)
+	}
+}
@@ -0,0 +1,122 @@
+package errors
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// responseJSON is the client-facing body written by Response
+type responseJSON struct {
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Details    []Detail        `json:"details,omitempty"`
+	Help       string          `json:"help,omitempty"`
+	Suggestion string          `json:"suggestion,omitempty"`
+	Conflict   *ConflictTarget `json:"conflict_target,omitempty"`
+	MaxSize    int64           `json:"max_size,omitempty"`
+	ID         string          `json:"error_id,omitempty"`
+	MsgKey     string          `json:"msg_key,omitempty"`
+	Slug       string          `json:"slug,omitempty"`
+}
+
+// Response returns the status code and JSON body to answer a request with.
+// Only client-safe fields are included: the message is the one from
+// ClientMessage, with no cause nor stack. Keys are renamed as set with
+// SetJSONFieldNames. Errors that aren't typed become a generic 500, and nil
+// gives (200, nil)
+func Response(err error) (status int, body []byte) {
+	if err == nil {
+		return http.StatusOK, nil
+	}
+
+	status = GetCode(err)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	resp := responseJSON{Code: status, Message: ClientMessage(err)}
+	if e := errRef(err); e != nil {
+		resp.Details = e.Details
+		resp.Help = e.HelpURL
+		resp.Suggestion = e.Suggestion
+		resp.Conflict = e.Conflict
+		resp.MaxSize = e.MaxSize
+		resp.ID = e.ID
+		resp.MsgKey = e.MsgKey
+		resp.Slug = e.Slug
+	}
+
+	body, e := json.Marshal(resp)
+	if e != nil {
+		status = http.StatusInternalServerError
+		body = []byte(`{"code":500,"message":"Internal Server Error"}`)
+	}
+	if jsonFieldNames != nil {
+		if renamed, e := renameKeys(body, jsonFieldNames); e == nil {
+			body = renamed
+		}
+	}
+	return status, body
+}
+
+// ClientMessage returns the message safe to show API clients: the one set with
+// PublicMsg, or else the status text of the error's code. Non-standard codes,
+// like 499, use the text of their class, e.g. "Bad Request" for 4xx
+func ClientMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	if msg := extractErr(err).PublicMessage; msg != "" {
+		return msg
+	}
+	code := GetCode(err)
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	if text := http.StatusText(code); text != "" {
+		return text
+	}
+	if text := http.StatusText(code / 100 * 100); text != "" {
+		return text
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+// IsSuccess reports whether err is nil or carries a 2xx code, like NoContent
+// and Accepted
+func IsSuccess(err error) bool {
+	if err == nil {
+		return true
+	}
+	code := GetCode(err)
+	return code >= 200 && code < 300
+}
+
+// WriteHTTP writes the response for err, as returned by Response, along with
+// the headers its options call for
+func WriteHTTP(w http.ResponseWriter, err error) {
+	status, body := Response(err)
+
+	e := extractErr(err)
+	if e.Location != "" {
+		w.Header().Set("Location", e.Location)
+	}
+	if len(e.Allow) > 0 {
+		w.Header().Set("Allow", strings.Join(e.Allow, ", "))
+	}
+	if e.RetryAfter > 0 {
+		seconds := int64(math.Ceil(e.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+
+	if body == nil || status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
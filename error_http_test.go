@@ -0,0 +1,102 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestResponseFieldNames(t *testing.T) {
+	if e := SetJSONFieldNames(map[string]string{"message": "error", "code": "status"}); e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { _ = SetJSONFieldNames(nil) })
+
+	status, body := Response(NewNotFound("missing", ErrorID("id-1")))
+	if status != 404 {
+		t.Errorf("status = %d, want 404", status)
+	}
+	var decoded map[string]interface{}
+	if e := json.Unmarshal(body, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded["status"] != float64(404) || decoded["error"] != "Not Found" || decoded["error_id"] != "id-1" {
+		t.Errorf("body = %s, want renamed status and error keys", body)
+	}
+	if _, ok := decoded["message"]; ok {
+		t.Errorf("body = %s still has the default message key", body)
+	}
+}
+
+func TestWriteHTTPNoContentLocation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, NewNoContent(WithLocation("/users/1")))
+
+	if rec.Code != 204 {
+		t.Errorf("status = %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/users/1" {
+		t.Errorf("Location = %q, want %q", got, "/users/1")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want none", rec.Body.String())
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	response := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	for _, status := range []int{100, 200, 204, 301, 304} {
+		if err := FromResponse(response(status, "")); err != nil {
+			t.Errorf("FromResponse(%d) = %v, want nil", status, err)
+		}
+	}
+	if err := FromResponse(nil); err != nil {
+		t.Errorf("FromResponse(nil) = %v, want nil", err)
+	}
+
+	err := FromResponse(response(404, "no such user"))
+	if !IsNotFound(err) || GetMessage(err) != "Not Found" || GetCause(err) != "no such user" {
+		t.Errorf("FromResponse(404) = %T with message %q and cause %q", err, GetMessage(err), GetCause(err))
+	}
+
+	err = FromResponse(response(503, "x"+strings.Repeat("é", 600)))
+	cause := GetCause(err)
+	if !IsInternal(err) || GetCode(err) != 503 {
+		t.Errorf("FromResponse(503) = %T with code %d", err, GetCode(err))
+	}
+	if !utf8.ValidString(cause) || !strings.HasSuffix(cause, "...") || len(cause) > maxResponseBody+len("...") {
+		t.Errorf("cause of %d bytes isn't cut on a rune boundary: %q", len(cause), cause)
+	}
+}
+
+func TestClientMessageNonStandardCodes(t *testing.T) {
+	NewDegraded := RegisterType("degraded_test", 599)
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{NewClientClosedRequest("gone"), "Bad Request"},
+		{NewDegraded("slow"), "Internal Server Error"},
+		{NewNotFound("x", 299), "OK"},
+		{NewNotFound("x", 999), "Internal Server Error"},
+		{NewNotFound("x", PublicMsg("no such user")), "no such user"},
+	}
+	for _, tt := range tests {
+		if got := ClientMessage(tt.err); got != tt.want {
+			t.Errorf("ClientMessage(code %d) = %q, want %q", GetCode(tt.err), got, tt.want)
+		}
+	}
+
+	_, body := Response(NewClientClosedRequest("gone"))
+	if !strings.Contains(string(body), `"message":"Bad Request"`) {
+		t.Errorf("Response body = %s, want a non-empty message", body)
+	}
+}
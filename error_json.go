@@ -0,0 +1,320 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// errJSON is the serialized shape of an error returned by GetJSON
+type errJSON struct {
+	Kind       string                 `json:"kind,omitempty"`
+	Code       int                    `json:"code,omitempty"`
+	Message    string                 `json:"message"`
+	Cause      string                 `json:"cause,omitempty"`
+	Stack      []ErrTrace             `json:"stack,omitempty"`
+	Details    []Detail               `json:"details,omitempty"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+	Tags       []string               `json:"tags,omitempty"`
+	Help       string                 `json:"help,omitempty"`
+	Suggestion string                 `json:"suggestion,omitempty"`
+	Conflict   *ConflictTarget        `json:"conflict_target,omitempty"`
+	MaxSize    int64                  `json:"max_size,omitempty"`
+	TraceID    string                 `json:"trace_id,omitempty"`
+	SpanID     string                 `json:"span_id,omitempty"`
+	Domain     string                 `json:"domain,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Timestamp  *time.Time             `json:"timestamp,omitempty"`
+	ID         string                 `json:"error_id,omitempty"`
+	MsgKey     string                 `json:"msg_key,omitempty"`
+	Slug       string                 `json:"slug,omitempty"`
+	Host       *HostInfo              `json:"host,omitempty"`
+	Version    string                 `json:"service_version,omitempty"`
+	Commit     string                 `json:"service_commit,omitempty"`
+}
+
+func toErrJSON(err error) errJSON {
+	if errRef(err) == nil {
+		return errJSON{Code: GetCode(err), Message: GetMessage(err), Version: serviceVersion, Commit: serviceCommit}
+	}
+	e := extractErr(err)
+	var timestamp *time.Time
+	if !e.Timestamp.IsZero() {
+		timestamp = &e.Timestamp
+	}
+	return errJSON{
+		Kind:       kindOf(err),
+		Code:       GetCode(err),
+		Message:    e.Message,
+		Cause:      e.Cause,
+		Stack:      e.Stack,
+		Details:    e.Details,
+		Fields:     e.Fields,
+		Tags:       e.Tags,
+		Help:       e.HelpURL,
+		Suggestion: e.Suggestion,
+		Conflict:   e.Conflict,
+		MaxSize:    e.MaxSize,
+		TraceID:    e.TraceID,
+		SpanID:     e.SpanID,
+		Domain:     e.Domain,
+		RequestID:  e.RequestID,
+		Timestamp:  timestamp,
+		ID:         e.ID,
+		MsgKey:     e.MsgKey,
+		Slug:       e.Slug,
+		Host:       e.Host,
+		Version:    serviceVersion,
+		Commit:     serviceCommit,
+	}
+}
+
+// jsonFieldNames maps the default JSON keys to the ones set with SetJSONFieldNames
+var jsonFieldNames map[string]string
+
+// SetJSONFieldNames renames the keys of the JSON output, e.g.
+// {"message": "error", "code": "status"}, to match an existing API contract.
+// The renames apply to GetJSON, FastJSON and the bodies of Response and
+// WriteHTTP.
+// Unknown keys and renames that collide with another key are rejected.
+// Passing nil restores the default names
+func SetJSONFieldNames(names map[string]string) error {
+	if len(names) == 0 {
+		jsonFieldNames = nil
+		return nil
+	}
+
+	keys := errJSONKeys()
+	for key := range names {
+		if _, ok := keys[key]; !ok {
+			return fmt.Errorf("unknown JSON field %q", key)
+		}
+	}
+
+	renamed := map[string]string{}
+	used := map[string]string{}
+	for key := range keys {
+		name := key
+		if n, ok := names[key]; ok {
+			name = n
+		}
+		if name == "" {
+			return fmt.Errorf("empty JSON name for field %q", key)
+		}
+		if other, ok := used[name]; ok {
+			return fmt.Errorf("JSON fields %q and %q would both be named %q", other, key, name)
+		}
+		used[name] = key
+		renamed[key] = name
+	}
+
+	jsonFieldNames = renamed
+	return nil
+}
+
+// errJSONKeys returns the default top-level keys of the JSON output
+func errJSONKeys() map[string]struct{} {
+	keys := map[string]struct{}{}
+	t := reflect.TypeOf(errJSON{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		keys[name] = struct{}{}
+	}
+	return keys
+}
+
+// jsonKey returns the name set with SetJSONFieldNames for a default key
+func jsonKey(key string) string {
+	if name, ok := jsonFieldNames[key]; ok {
+		return name
+	}
+	return key
+}
+
+// renameKeys re-encodes a JSON object with its keys renamed through names
+func renameKeys(data []byte, names map[string]string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return data, err
+	}
+	renamed := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		if name, ok := names[key]; ok {
+			key = name
+		}
+		renamed[key] = value
+	}
+	return json.Marshal(renamed)
+}
+
+func encodeJSON(err error) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	encoded, e := json.Marshal(toErrJSON(err))
+	if e != nil || jsonFieldNames == nil {
+		return encoded, e
+	}
+	return renameKeys(encoded, jsonFieldNames)
+}
+
+// GetJSON returns the error as JSON bytes, or nil if it can't be encoded
+func GetJSON(err error) []byte {
+	encoded, e := encodeJSON(err)
+	if e != nil {
+		return nil
+	}
+	return encoded
+}
+
+// MustJSON works like GetJSON but panics if the error can't be encoded
+func MustJSON(err error) []byte {
+	encoded, e := encodeJSON(err)
+	if e != nil {
+		panic(e)
+	}
+	return encoded
+}
+
+// remoteError stands in for a wrapped error rebuilt from JSON. It only keeps
+// the cause text, so errors.Is against the original sentinel won't match
+type remoteError struct {
+	msg string
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+// FromJSON rebuilds a typed error from the output of GetJSON. The type is
+// chosen from the kind, falling back to the code, and the cause, if any, is wrapped as a remote error:
+// Unwrap returns it, but errors.Is can't match errors from the other side.
+// The second return value reports decoding failures
+func FromJSON(data []byte) (error, error) {
+	if jsonFieldNames != nil {
+		defaults := make(map[string]string, len(jsonFieldNames))
+		for key, name := range jsonFieldNames {
+			defaults[name] = key
+		}
+		renamed, err := renameKeys(data, defaults)
+		if err != nil {
+			return nil, err
+		}
+		data = renamed
+	}
+
+	var decoded *errJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return nil, nil
+	}
+
+	fields := []interface{}{decoded.Message}
+	if decoded.Cause != "" {
+		fields = append(fields, &remoteError{msg: decoded.Cause})
+	}
+	if len(decoded.Details) > 0 {
+		fields = append(fields, WithDetails(decoded.Details...))
+	}
+	if len(decoded.Fields) > 0 {
+		fields = append(fields, Fields(decoded.Fields))
+	}
+	if len(decoded.Tags) > 0 {
+		fields = append(fields, Tags(decoded.Tags...))
+	}
+	if decoded.Slug != "" {
+		fields = append(fields, Slug(decoded.Slug))
+	}
+	if decoded.MsgKey != "" {
+		fields = append(fields, MsgKey(decoded.MsgKey))
+	}
+	if decoded.ID != "" {
+		fields = append(fields, ErrorID(decoded.ID))
+	}
+	if decoded.RequestID != "" {
+		fields = append(fields, RequestID(decoded.RequestID))
+	}
+	if decoded.Domain != "" {
+		fields = append(fields, Domain(decoded.Domain))
+	}
+	if decoded.Help != "" {
+		fields = append(fields, WithHelpURL(decoded.Help))
+	}
+	if decoded.Suggestion != "" {
+		fields = append(fields, WithSuggestion(decoded.Suggestion))
+	}
+	if decoded.Conflict != nil {
+		fields = append(fields, WithConflictTarget(decoded.Conflict.Resource, decoded.Conflict.ID))
+	}
+	if decoded.MaxSize != 0 {
+		fields = append(fields, MaxSize(decoded.MaxSize))
+	}
+	if decoded.TraceID != "" || decoded.SpanID != "" {
+		fields = append(fields, WithTraceID(decoded.TraceID), WithSpanID(decoded.SpanID))
+	}
+
+	var err error
+	if code, ok := registeredType(decoded.Kind); ok {
+		if decoded.Code != 0 {
+			code = decoded.Code
+		}
+		err = &Custom{Err: parseFields(fields, code), Kind: decoded.Kind}
+	} else if decoded.Code != 0 {
+		err = newFromKind(decoded.Kind, append(fields, decoded.Code)...)
+	} else {
+		err = newFromKind(decoded.Kind, fields...)
+	}
+	if err == nil {
+		err = NewFromStatus(decoded.Code, fields...)
+	}
+	if decoded.Timestamp != nil {
+		errRef(err).Timestamp = *decoded.Timestamp
+	}
+	if decoded.Host != nil {
+		errRef(err).Host = decoded.Host
+	}
+	return ReplaceStack(err, decoded.Stack), nil
+}
+
+type detailedFrame struct {
+	Index    int    `json:"index"`
+	File     string `json:"file"`
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+}
+
+type detailedStack struct {
+	Code         int             `json:"code"`
+	Message      string          `json:"message"`
+	StackMessage string          `json:"stack_message,omitempty"`
+	RequestID    string          `json:"correlation_id,omitempty"`
+	Frames       []detailedFrame `json:"frames"`
+}
+
+// GetStackJSONDetailed returns an object with the code, message, stack message
+// and the indexed stack frames, ready for log ingestion
+func GetStackJSONDetailed(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	e := extractErr(err)
+	detailed := detailedStack{
+		Code:         GetCode(err),
+		Message:      GetMessage(err),
+		StackMessage: e.StackMessage,
+		RequestID:    e.RequestID,
+		Frames:       make([]detailedFrame, 0, len(e.Stack)),
+	}
+	for i, trace := range e.Stack {
+		detailed.Frames = append(detailed.Frames, detailedFrame{Index: i, File: trace.File, Function: trace.Function, Line: trace.Line})
+	}
+
+	encoded, _ := json.Marshal(detailed)
+
+	return string(encoded)
+}
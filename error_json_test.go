@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+)
+
+func TestMarshalNotFoundCode(t *testing.T) {
+	err := NewNotFound()
+
+	encoded, e := json.Marshal(err)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var decoded struct {
+		Code int `json:"code"`
+	}
+	if e := json.Unmarshal(encoded, &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded.Code != 404 {
+		t.Errorf("json.Marshal code = %d, want 404 in %s", decoded.Code, encoded)
+	}
+
+	decoded.Code = 0
+	if e := json.Unmarshal(GetJSON(err), &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded.Code != 404 {
+		t.Errorf("GetJSON code = %d, want 404", decoded.Code)
+	}
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	trace := Trace()
+	original := NewNotFound("user missing", sql.ErrNoRows, trace, WithDetails(Detail{Field: "id", Reason: "unknown"}))
+
+	rebuilt, e := FromJSON(GetJSON(original))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !IsNotFound(rebuilt) {
+		t.Fatalf("rebuilt %T, want *NotFound", rebuilt)
+	}
+	if diff := DiffStack(original, rebuilt); diff != "" {
+		t.Errorf("rebuilt error differs:\n%s", diff)
+	}
+	if got := GetDetails(rebuilt); len(got) != 1 || got[0].Field != "id" {
+		t.Errorf("details = %v, want the id detail", got)
+	}
+	if GetErrorID(rebuilt) != GetErrorID(original) {
+		t.Errorf("error ID = %q, want %q", GetErrorID(rebuilt), GetErrorID(original))
+	}
+
+	wrapped := stderrors.Unwrap(rebuilt)
+	if wrapped == nil || wrapped.Error() != sql.ErrNoRows.Error() {
+		t.Errorf("Unwrap = %v, want the remote cause", wrapped)
+	}
+	if stderrors.Is(rebuilt, sql.ErrNoRows) {
+		t.Error("errors.Is matched a sentinel across the JSON boundary")
+	}
+}
+
+func TestFromJSONKeepsKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{NewFatal("f"), "fatal"},
+		{NewUnauthorized("u"), "unauthorized"},
+		{NewForbidden("f"), "forbidden"},
+		{NewInternal("i"), "internal"},
+		{NewNotFound("n", 410), "not_found"},
+	}
+	for _, tt := range tests {
+		rebuilt, e := FromJSON(GetJSON(tt.err))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if got := kindOf(rebuilt); got != tt.want {
+			t.Errorf("kind = %q, want %q", got, tt.want)
+		}
+		if GetCode(rebuilt) != GetCode(tt.err) {
+			t.Errorf("%s: code = %d, want %d", tt.want, GetCode(rebuilt), GetCode(tt.err))
+		}
+	}
+}
+
+func TestFromJSONUnknownKindUsesCode(t *testing.T) {
+	rebuilt, e := FromJSON([]byte(`{"kind":"gone","code":404,"message":"x"}`))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !IsNotFound(rebuilt) {
+		t.Errorf("rebuilt %T, want *NotFound", rebuilt)
+	}
+}
@@ -0,0 +1,51 @@
+package errors
+
+// The Must helpers panic with the full error information when err isn't of
+// the expected kind. They are meant for tests, fixtures and examples, not for
+// production code paths
+
+// MustBe panics unless err is of the given kind, e.g. "not_found". An empty
+// kind always panics, as no error has it
+func MustBe(err error, kind string) {
+	if kind == "" {
+		panic("errors: MustBe needs a kind")
+	}
+	if kindOf(err) == kind {
+		return
+	}
+	if err == nil {
+		panic("expected " + kind + " error, got nil")
+	}
+	panic(ErrorF(err))
+}
+
+func MustInternal(err error)                   { MustBe(err, "internal") }
+func MustNotFound(err error)                   { MustBe(err, "not_found") }
+func MustConflict(err error)                   { MustBe(err, "conflict") }
+func MustBadRequest(err error)                 { MustBe(err, "bad_request") }
+func MustUnauthorized(err error)               { MustBe(err, "unauthorized") }
+func MustFatal(err error)                      { MustBe(err, "fatal") }
+func MustNoContent(err error)                  { MustBe(err, "no_content") }
+func MustPaymentRequired(err error)            { MustBe(err, "payment_required") }
+func MustClientClosedRequest(err error)        { MustBe(err, "client_closed_request") }
+func MustRangeNotSatisfiable(err error)        { MustBe(err, "range_not_satisfiable") }
+func MustUnsupportedMediaType(err error)       { MustBe(err, "unsupported_media_type") }
+func MustAccepted(err error)                   { MustBe(err, "accepted") }
+func MustRequestTimeout(err error)             { MustBe(err, "request_timeout") }
+func MustTooManyRequests(err error)            { MustBe(err, "too_many_requests") }
+func MustUnauthenticated(err error)            { MustBe(err, "unauthenticated") }
+func MustBadGateway(err error)                 { MustBe(err, "bad_gateway") }
+func MustPayloadTooLarge(err error)            { MustBe(err, "payload_too_large") }
+func MustMethodNotAllowed(err error)           { MustBe(err, "method_not_allowed") }
+func MustTooEarly(err error)                   { MustBe(err, "too_early") }
+func MustPreconditionRequired(err error)       { MustBe(err, "precondition_required") }
+func MustUnavailableForLegalReasons(err error) { MustBe(err, "unavailable_for_legal_reasons") }
+
+// MustForbidden accepts what IsForbidden does, including Unauthorized errors
+// that kept their legacy 403 code
+func MustForbidden(err error) {
+	if IsForbidden(err) {
+		return
+	}
+	MustBe(err, "forbidden")
+}
@@ -0,0 +1,45 @@
+package errors
+
+import (
+	stderrors "errors"
+	"testing"
+)
+
+// panics reports whether fn panics
+func panics(fn func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	fn()
+	return false
+}
+
+func TestMustBe(t *testing.T) {
+	tests := []struct {
+		name  string
+		fn    func()
+		panic bool
+	}{
+		{"match", func() { MustBe(NewNotFound("x"), "not_found") }, false},
+		{"mismatch", func() { MustBe(NewConflict("x"), "not_found") }, true},
+		{"not typed", func() { MustBe(stderrors.New("x"), "not_found") }, true},
+		{"nil", func() { MustBe(nil, "not_found") }, true},
+		{"empty kind", func() { MustBe(nil, "") }, true},
+		{"empty kind and plain error", func() { MustBe(stderrors.New("x"), "") }, true},
+		{"registered kind", func() { MustBe(RegisterType("must_test", 418)("x"), "must_test") }, false},
+		{"MustNotFound match", func() { MustNotFound(NewNotFound("x")) }, false},
+		{"MustNotFound mismatch", func() { MustNotFound(NewInternal("x")) }, true},
+		{"MustConflict match", func() { MustConflict(NewConflict("x")) }, false},
+		{"MustForbidden match", func() { MustForbidden(NewForbidden("x")) }, false},
+		{"MustForbidden legacy 403 unauthorized", func() { MustForbidden(NewUnauthorized("x")) }, false},
+		{"MustForbidden 401 unauthorized", func() { MustForbidden(NewUnauthorized("x", 401)) }, true},
+		{"MustForbidden nil", func() { MustForbidden(nil) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := panics(tt.fn); got != tt.panic {
+				t.Errorf("panicked = %v, want %v", got, tt.panic)
+			}
+		})
+	}
+}
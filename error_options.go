@@ -0,0 +1,498 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option sets extra information on an error. Options are passed to the
+// constructors along with the other fields, e.g. NewNotFound("user not found", WithReason("..."))
+type Option func(e *Err)
+
+// PublicMsg sets the message shown to API clients by Response, keeping the
+// regular message, which may hold internal details, for operators
+func PublicMsg(msg string) Option {
+	return func(e *Err) {
+		e.PublicMessage = msg
+	}
+}
+
+// WithReason records a developer-only explanation. It is printed by ErrorF but
+// never by Error or the JSON output
+func WithReason(reason string) Option {
+	return func(e *Err) {
+		e.Reason = reason
+	}
+}
+
+// GetReason returns the reason set with WithReason
+func GetReason(err error) string {
+	return extractErr(err).Reason
+}
+
+// Detail describes a problem with a single field of a request
+type Detail struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// WithDetails appends field-level details to the error
+func WithDetails(details ...Detail) Option {
+	return func(e *Err) {
+		e.Details = append(e.Details, details...)
+	}
+}
+
+// GetDetails returns the details attached with WithDetails
+func GetDetails(err error) []Detail {
+	return extractErr(err).Details
+}
+
+// WithHelpURL links the error to documentation on how to address it
+func WithHelpURL(url string) Option {
+	return func(e *Err) {
+		e.HelpURL = url
+	}
+}
+
+// GetHelpURL returns the URL set with WithHelpURL
+func GetHelpURL(err error) string {
+	return extractErr(err).HelpURL
+}
+
+// WithAlert overrides whether ShouldAlert reports the error
+func WithAlert(alert bool) Option {
+	return func(e *Err) {
+		e.Alert = &alert
+	}
+}
+
+// ShouldAlert reports whether the error is worth paging on. Unless set with
+// WithAlert, that's the case for Fatal errors and codes of 500 and above
+func ShouldAlert(err error) bool {
+	if err == nil {
+		return false
+	}
+	if e := errRef(err); e != nil && e.Alert != nil {
+		return *e.Alert
+	}
+	return IsFatal(err) || GetCode(err) >= 500
+}
+
+// WithPayload attaches an arbitrary value to the error, retrieved with Payload.
+// The payload is never serialized
+func WithPayload(v interface{}) Option {
+	return func(e *Err) {
+		e.Payload = v
+	}
+}
+
+// Payload returns the payload attached with WithPayload if it is of type T
+func Payload[T any](err error) (T, bool) {
+	v, ok := extractErr(err).Payload.(T)
+	return v, ok
+}
+
+// WithMaxStack caps how many frames the error keeps, overriding SetMaxStackDepth
+func WithMaxStack(n int) Option {
+	return func(e *Err) {
+		e.MaxStack = n
+	}
+}
+
+// ClearCause removes the cause and the wrapped error set by earlier fields
+func ClearCause() Option {
+	return func(e *Err) {
+		e.Cause = ""
+		e.Wrapped = nil
+	}
+}
+
+// CauseWrap wraps err, so errors.Is and Unwrap still reach it, while using the
+// formatted text as the cause instead of err.Error()
+func CauseWrap(err error, format string, args ...interface{}) Option {
+	return func(e *Err) {
+		e.Wrapped = err
+		e.Cause = fmt.Sprintf(format, args...)
+	}
+}
+
+// CauseF wraps err and prefixes its text with formatted context, e.g.
+// CauseF(err, "reading config %s", path) gives "reading config app.yml: <err>".
+// Unlike CauseWrap, the original cause text is kept. A nil err is ignored
+func CauseF(err error, format string, args ...interface{}) Option {
+	return func(e *Err) {
+		if err == nil {
+			return
+		}
+		e.Wrapped = err
+		e.Cause = fmt.Sprintf(format, args...) + ": " + err.Error()
+	}
+}
+
+// Causes wraps several underlying errors, e.g. the failures of parallel calls,
+// with their messages joined as the cause. They're joined with errors.Join:
+// Err already has Unwrap() error, so it can't also have Unwrap() []error, but
+// errors.Is and errors.As reach every one of them through the joined error.
+// Nil errors are skipped
+func Causes(errs ...error) Option {
+	return func(e *Err) {
+		joined := stderrors.Join(errs...)
+		if joined == nil {
+			return
+		}
+		var causes []string
+		for _, err := range errs {
+			if err != nil {
+				causes = append(causes, err.Error())
+			}
+		}
+		e.Wrapped = joined
+		e.Cause = strings.Join(causes, "; ")
+	}
+}
+
+// WithTraceID records the distributed trace the error happened in
+func WithTraceID(id string) Option {
+	return func(e *Err) {
+		e.TraceID = id
+	}
+}
+
+// WithSpanID records the span the error happened in
+func WithSpanID(id string) Option {
+	return func(e *Err) {
+		e.SpanID = id
+	}
+}
+
+// TraceContext records both the trace and span the error happened in
+func TraceContext(traceID, spanID string) Option {
+	return func(e *Err) {
+		e.TraceID = traceID
+		e.SpanID = spanID
+	}
+}
+
+// GetTraceID returns the ID set with WithTraceID
+func GetTraceID(err error) string {
+	return extractErr(err).TraceID
+}
+
+// GetSpanID returns the ID set with WithSpanID
+func GetSpanID(err error) string {
+	return extractErr(err).SpanID
+}
+
+// ConflictTarget identifies the resource a Conflict collided with
+type ConflictTarget struct {
+	Resource string `json:"resource"`
+	ID       string `json:"id"`
+}
+
+// WithConflictTarget records which resource the error conflicted with
+func WithConflictTarget(resource, id string) Option {
+	return func(e *Err) {
+		e.Conflict = &ConflictTarget{Resource: resource, ID: id}
+	}
+}
+
+// GetConflictTarget returns the resource set with WithConflictTarget, or nil
+func GetConflictTarget(err error) *ConflictTarget {
+	return extractErr(err).Conflict
+}
+
+// WithLocation records the URL where the result of an Accepted operation can be
+// polled. WriteHTTP sends it as the Location header
+func WithLocation(url string) Option {
+	return func(e *Err) {
+		e.Location = url
+	}
+}
+
+// GetLocation returns the URL set with WithLocation
+func GetLocation(err error) string {
+	return extractErr(err).Location
+}
+
+// WithSuggestion tells the client what to do about the error, e.g.
+// "check your API key"
+func WithSuggestion(suggestion string) Option {
+	return func(e *Err) {
+		e.Suggestion = suggestion
+	}
+}
+
+// GetSuggestion returns the suggestion set with WithSuggestion
+func GetSuggestion(err error) string {
+	return extractErr(err).Suggestion
+}
+
+// Hint is the same as WithSuggestion, for remediation guidance
+func Hint(hint string) Option {
+	return WithSuggestion(hint)
+}
+
+// GetHint is the same as GetSuggestion
+func GetHint(err error) string {
+	return GetSuggestion(err)
+}
+
+// RetryAfter tells clients how long to wait before retrying. WriteHTTP sends
+// it as the Retry-After header
+func RetryAfter(d time.Duration) Option {
+	return func(e *Err) {
+		e.RetryAfter = d
+	}
+}
+
+// GetRetryAfter returns the duration set with RetryAfter
+func GetRetryAfter(err error) time.Duration {
+	return extractErr(err).RetryAfter
+}
+
+// MaxSize records the largest payload size accepted, in bytes
+func MaxSize(n int64) Option {
+	return func(e *Err) {
+		e.MaxSize = n
+	}
+}
+
+// GetMaxSize returns the size set with MaxSize
+func GetMaxSize(err error) int64 {
+	return extractErr(err).MaxSize
+}
+
+// Allow records the methods a MethodNotAllowed resource supports. WriteHTTP
+// sends them as the Allow header
+func Allow(methods ...string) Option {
+	return func(e *Err) {
+		e.Allow = append(e.Allow, methods...)
+	}
+}
+
+// GetAllow returns the methods set with Allow
+func GetAllow(err error) []string {
+	return extractErr(err).Allow
+}
+
+// Fields attaches structured context, such as IDs, to the error
+func Fields(fields map[string]interface{}) Option {
+	return func(e *Err) {
+		for key, value := range fields {
+			setField(e, key, value)
+		}
+	}
+}
+
+// Field attaches a single key-value pair to the error
+func Field(key string, value interface{}) Option {
+	return func(e *Err) {
+		setField(e, key, value)
+	}
+}
+
+func setField(e *Err, key string, value interface{}) {
+	if e.Fields == nil {
+		e.Fields = map[string]interface{}{}
+	}
+	e.Fields[key] = value
+}
+
+// GetFields returns the fields set with Fields and Field
+func GetFields(err error) map[string]interface{} {
+	return extractErr(err).Fields
+}
+
+// Tags labels the error, e.g. "billing" or "external", for filtering
+func Tags(tags ...string) Option {
+	return func(e *Err) {
+		e.Tags = append(e.Tags, tags...)
+	}
+}
+
+// GetTags returns the labels set with Tags
+func GetTags(err error) []string {
+	return extractErr(err).Tags
+}
+
+// HasTag reports whether the error was labeled with tag
+func HasTag(err error, tag string) bool {
+	for _, t := range extractErr(err).Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Retryable marks the error as worth retrying, overriding the default of IsRetryable
+func Retryable() Option {
+	return func(e *Err) {
+		retryable := true
+		e.Retryable = &retryable
+	}
+}
+
+// NotRetryable marks the error as not worth retrying, overriding the default
+// of IsRetryable
+func NotRetryable() Option {
+	return func(e *Err) {
+		retryable := false
+		e.Retryable = &retryable
+	}
+}
+
+// IsRetryable reports whether the operation that failed may succeed if retried.
+// Unless set with Retryable or NotRetryable, that's the case for codes 408,
+// 425, 429, 502, 503 and 504
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if e := errRef(err); e != nil && e.Retryable != nil {
+		return *e.Retryable
+	}
+	switch GetCode(err) {
+	case 408, 425, 429, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// Op records the logical operation that failed, e.g. "users.Load"
+func Op(op string) Option {
+	return func(e *Err) {
+		e.Op = op
+	}
+}
+
+// GetOps returns the operations recorded with Op, StackOp and HereOp along the
+// chain of wrapped errors, outermost first, giving the logical path that led
+// to the error
+func GetOps(err error) []string {
+	var ops []string
+	for err != nil {
+		if e := errRef(err); e != nil {
+			for i := len(e.Ops) - 1; i >= 0; i-- {
+				ops = append(ops, e.Ops[i])
+			}
+			if e.Op != "" {
+				ops = append(ops, e.Op)
+			}
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return ops
+}
+
+// Domain records the subsystem the error belongs to, e.g. "payments"
+func Domain(domain string) Option {
+	return func(e *Err) {
+		e.Domain = domain
+	}
+}
+
+// GetDomain returns the subsystem set with Domain
+func GetDomain(err error) string {
+	return extractErr(err).Domain
+}
+
+// RequestID records the ID of the request the error originated in
+func RequestID(id string) Option {
+	return func(e *Err) {
+		e.RequestID = id
+	}
+}
+
+// GetRequestID returns the ID set with RequestID
+func GetRequestID(err error) string {
+	return extractErr(err).RequestID
+}
+
+// GetTimestamp returns the UTC time the error was constructed
+func GetTimestamp(err error) time.Time {
+	return extractErr(err).Timestamp
+}
+
+// ErrorID overrides the unique ID generated for the error, e.g. to keep the
+// one received from another service
+func ErrorID(id string) Option {
+	return func(e *Err) {
+		e.ID = id
+	}
+}
+
+// GetErrorID returns the unique ID of the error instance, to match an ID
+// reported by a user with the server logs
+func GetErrorID(err error) string {
+	return extractErr(err).ID
+}
+
+// MsgKey records a stable translation key for the message, e.g.
+// "errors.user.not_found", so clients can localize it
+func MsgKey(key string) Option {
+	return func(e *Err) {
+		e.MsgKey = key
+	}
+}
+
+// GetMsgKey returns the translation key set with MsgKey
+func GetMsgKey(err error) string {
+	return extractErr(err).MsgKey
+}
+
+// Slug records a stable application-level code, e.g. "user_not_found", for
+// clients to branch on regardless of the HTTP status
+func Slug(slug string) Option {
+	return func(e *Err) {
+		e.Slug = slug
+	}
+}
+
+// GetSlug returns the code set with Slug
+func GetSlug(err error) string {
+	return extractErr(err).Slug
+}
+
+// HostInfo identifies where an error was created, to aggregate serialized
+// errors coming from several nodes
+type HostInfo struct {
+	Hostname string `json:"hostname,omitempty"`
+	Pod      string `json:"pod,omitempty"`
+	Env      string `json:"env,omitempty"`
+}
+
+var (
+	hostname     string
+	hostnameOnce sync.Once
+)
+
+func currentHost() *HostInfo {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+	})
+	return &HostInfo{Hostname: hostname, Pod: os.Getenv(hostPodVar), Env: os.Getenv(hostEnvVar)}
+}
+
+// CaptureHost records the hostname, pod name and environment of the error,
+// read from the variables set with SetHostEnvVars. SetCaptureHost does it for
+// every error
+func CaptureHost() Option {
+	return func(e *Err) {
+		e.Host = currentHost()
+	}
+}
+
+// GetHost returns the host captured with CaptureHost or SetCaptureHost, or a
+// zero HostInfo if none was
+func GetHost(err error) HostInfo {
+	if host := extractErr(err).Host; host != nil {
+		return *host
+	}
+	return HostInfo{}
+}
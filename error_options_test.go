@@ -0,0 +1,59 @@
+package errors
+
+import (
+	stderrors "errors"
+	"testing"
+)
+
+func TestShouldAlert(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", stderrors.New("x"), false},
+		{"4xx", NewNotFound("x"), false},
+		{"5xx", NewInternal("x"), true},
+		{"bad gateway", NewBadGateway("x"), true},
+		{"fatal", NewFatal("x"), true},
+		{"fatal with a 4xx code", NewFatal("x", 400), true},
+		{"4xx forced on", NewNotFound("x", WithAlert(true)), true},
+		{"5xx forced off", NewInternal("x", WithAlert(false)), false},
+		{"fatal forced off", NewFatal("x", WithAlert(false)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldAlert(tt.err); got != tt.want {
+				t.Errorf("ShouldAlert = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOpsWhileStacking(t *testing.T) {
+	err := NewNotFound("user missing", Op("repo.FindUser"))
+	StackOp(err, "users.Load", ErrTrace{Function: "Load", Line: 1})
+	Stack(err, ErrTrace{Function: "helper", Line: 2})
+	HereOp(err, "api.GetUser")
+	StackOp(err, "", ErrTrace{Function: "noop", Line: 3})
+
+	want := []string{"api.GetUser", "users.Load", "repo.FindUser"}
+	got := GetOps(err)
+	if len(got) != len(want) {
+		t.Fatalf("GetOps = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("GetOps = %v, want %v", got, want)
+		}
+	}
+	if n := len(GetStack(err)); n != 4 {
+		t.Errorf("stack has %d frames, want every StackOp and HereOp frame kept", n)
+	}
+
+	outer := NewInternal("load failed", err, Op("handler.Serve"))
+	if got := GetOps(outer); len(got) != 4 || got[0] != "handler.Serve" || got[1] != "api.GetUser" {
+		t.Errorf("GetOps across wrapped errors = %v", got)
+	}
+}
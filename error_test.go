@@ -0,0 +1,379 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetCodeJoined(t *testing.T) {
+	err := stderrors.Join(stderrors.New("plain"), NewNotFound("user missing"))
+	if got := GetCode(err); got != 404 {
+		t.Errorf("GetCode = %d, want 404", got)
+	}
+	if got := GetCode(stderrors.Join(stderrors.New("a"), stderrors.New("b"))); got != 0 {
+		t.Errorf("GetCode without typed children = %d, want 0", got)
+	}
+}
+
+func TestGetJSONJoined(t *testing.T) {
+	err := stderrors.Join(stderrors.New("plain"), NewNotFound("user missing"))
+
+	var decoded errJSON
+	if e := json.Unmarshal(GetJSON(err), &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded.Code != 404 {
+		t.Errorf("code = %d, want 404", decoded.Code)
+	}
+	if decoded.Message != "plain; user missing" {
+		t.Errorf("message = %q, want %q", decoded.Message, "plain; user missing")
+	}
+}
+
+func TestSentinelCodes(t *testing.T) {
+	SetSentinelCodes(true)
+	t.Cleanup(func() { SetSentinelCodes(false) })
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{sql.ErrNoRows, 404},
+		{os.ErrNotExist, 404},
+		{os.ErrPermission, 403},
+		{context.Canceled, 499},
+		{context.DeadlineExceeded, 504},
+		{fmt.Errorf("query: %w", sql.ErrNoRows), 404},
+		{stderrors.New("plain"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := GetCode(tt.err); got != tt.want {
+				t.Errorf("GetCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentinelCodesDisabled(t *testing.T) {
+	if got := GetCode(sql.ErrNoRows); got != 0 {
+		t.Errorf("GetCode = %d, want 0 while sentinel codes are off", got)
+	}
+}
+
+func TestGetJSONSentinel(t *testing.T) {
+	SetSentinelCodes(true)
+	t.Cleanup(func() { SetSentinelCodes(false) })
+
+	var decoded errJSON
+	if e := json.Unmarshal(GetJSON(sql.ErrNoRows), &decoded); e != nil {
+		t.Fatal(e)
+	}
+	if decoded.Code != 404 || decoded.Message != sql.ErrNoRows.Error() {
+		t.Errorf("got code %d and message %q, want 404 and %q", decoded.Code, decoded.Message, sql.ErrNoRows.Error())
+	}
+}
+
+func TestWithMaxStackOverridesGlobal(t *testing.T) {
+	SetMaxStackDepth(2)
+	t.Cleanup(func() { SetMaxStackDepth(0) })
+
+	shallow := NewInternal("shallow")
+	deep := NewInternal("deep", WithMaxStack(5))
+	for i := 0; i < 10; i++ {
+		Stack(shallow, ErrTrace{Function: "f", Line: i + 1})
+		Stack(deep, ErrTrace{Function: "f", Line: i + 1})
+	}
+	if got := len(GetStack(shallow)); got != 2 {
+		t.Errorf("global cap kept %d frames, want 2", got)
+	}
+	if got := len(GetStack(deep)); got != 5 {
+		t.Errorf("per-error cap kept %d frames, want 5", got)
+	}
+}
+
+func TestGetMessageJoined(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"none", stderrors.Join(), ""},
+		{"one", stderrors.Join(NewBadRequest("name is required")), "name is required"},
+		{"three and an empty one", stderrors.Join(NewBadRequest("name is required"), NewBadRequest(""), stderrors.New("age is negative"), NewConflict("email taken")), "name is required; age is negative; email taken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetMessage(tt.err); got != tt.want {
+				t.Errorf("GetMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinSeparator(t *testing.T) {
+	SetJoinSeparator(" | ")
+	t.Cleanup(func() { SetJoinSeparator("; ") })
+
+	err := stderrors.Join(NewBadRequest("a"), NewBadRequest("b"))
+	if got := GetMessage(err); got != "a | b" {
+		t.Errorf("GetMessage = %q, want %q", got, "a | b")
+	}
+}
+
+func TestDedupeOrigin(t *testing.T) {
+	trace := Trace()
+
+	err := NewNotFound("x", trace)
+	Stack(err, trace)
+	if got := len(GetStack(err)); got != 1 {
+		t.Errorf("stack has %d frames, want the origin once", got)
+	}
+
+	SetDedupeOrigin(false)
+	t.Cleanup(func() { SetDedupeOrigin(true) })
+	err = NewNotFound("x", trace)
+	Stack(err, trace)
+	if got := len(GetStack(err)); got != 2 {
+		t.Errorf("stack has %d frames with dedupe off, want 2", got)
+	}
+}
+
+func TestNewValidationError(t *testing.T) {
+	err := NewValidationError(Detail{Field: "email", Reason: "invalid"}, Detail{Field: "age", Reason: "negative"})
+
+	if got := GetCode(err); got != 400 {
+		t.Errorf("GetCode = %d, want 400", got)
+	}
+	if got := GetMessage(err); got != "validation failed" {
+		t.Errorf("GetMessage = %q, want %q", got, "validation failed")
+	}
+
+	rebuilt, e := FromJSON(GetJSON(err))
+	if e != nil {
+		t.Fatal(e)
+	}
+	details := GetDetails(rebuilt)
+	if len(details) != 2 || details[0] != (Detail{Field: "email", Reason: "invalid"}) || details[1].Field != "age" {
+		t.Errorf("details after round trip = %v", details)
+	}
+	if !IsBadRequest(rebuilt) || GetMessage(rebuilt) != "validation failed" {
+		t.Errorf("rebuilt %T with message %q", rebuilt, GetMessage(rebuilt))
+	}
+}
+
+func TestErrorFStackLimit(t *testing.T) {
+	SetErrorFStackLimit(10)
+	t.Cleanup(func() { SetErrorFStackLimit(0) })
+
+	err := NewInternal("deep")
+	for i := 0; i < 200; i++ {
+		Stack(err, ErrTrace{File: "deep.go", Function: fmt.Sprintf("frame%d", i), Line: i + 1})
+	}
+
+	output := ErrorF(err)
+	if !strings.Contains(output, "... 190 frames omitted ...") {
+		t.Errorf("ErrorF has no omission marker:\n%s", output)
+	}
+	if !strings.Contains(output, "frame0 ") || !strings.Contains(output, "frame199 ") {
+		t.Error("ErrorF dropped the first or last frame")
+	}
+	if strings.Contains(output, "frame100 ") {
+		t.Error("ErrorF kept a middle frame")
+	}
+	if got := strings.Count(output, "deep.go"); got != 10 {
+		t.Errorf("ErrorF printed %d frames, want 10", got)
+	}
+}
+
+func TestCodePriority(t *testing.T) {
+	err := stderrors.Join(NewNotFound("n"), NewInternal("i"), NewConflict("c"))
+	if got := GetCode(err); got != 500 {
+		t.Errorf("default priority picked %d, want 500", got)
+	}
+
+	// rank conflicts above everything, then the lowest code
+	SetCodePriority(func(a, b int) int {
+		if a == 409 || b == 409 {
+			if a == b {
+				return 0
+			}
+			if a == 409 {
+				return 1
+			}
+			return -1
+		}
+		return b - a
+	})
+	t.Cleanup(func() { SetCodePriority(nil) })
+
+	if got := GetCode(err); got != 409 {
+		t.Errorf("custom priority picked %d, want 409", got)
+	}
+	if got := GetCode(stderrors.Join(NewInternal("i"), NewNotFound("n"))); got != 404 {
+		t.Errorf("custom priority picked %d, want 404", got)
+	}
+}
+
+func TestNilCauseIsIgnored(t *testing.T) {
+	var missing error
+	err := NewInternal("x", stderrors.New("boom"), missing)
+	if got := GetCause(err); got != "boom" {
+		t.Errorf("GetCause = %q, want the earlier cause kept", got)
+	}
+	if CauseError(err) == nil {
+		t.Error("CauseError = nil, want the earlier wrapped error")
+	}
+}
+
+func TestClearCause(t *testing.T) {
+	err := NewInternal("x", stderrors.New("boom"), ClearCause())
+	if got := extractErr(err).Cause; got != "" {
+		t.Errorf("cause = %q, want it cleared", got)
+	}
+	if CauseError(err) != nil {
+		t.Error("CauseError is not nil after ClearCause")
+	}
+}
+
+func TestNoContentAccessors(t *testing.T) {
+	err := NewNoContent("gone", WithReason("deleted"), RequestID("req-1"), PublicMsg("nothing here"))
+
+	if got := GetReason(err); got != "deleted" {
+		t.Errorf("GetReason = %q, want %q", got, "deleted")
+	}
+	if got := GetRequestID(err); got != "req-1" {
+		t.Errorf("GetRequestID = %q, want %q", got, "req-1")
+	}
+	if GetErrorID(err) == "" || GetTimestamp(err).IsZero() {
+		t.Error("NoContent has no error ID or timestamp")
+	}
+	if got := ClientMessage(err); got != "nothing here" {
+		t.Errorf("ClientMessage = %q, want %q", got, "nothing here")
+	}
+	if got := GetMessage(err); got != "gone" {
+		t.Errorf("GetMessage = %q, want %q", got, "gone")
+	}
+}
+
+func TestGetStackCollapsed(t *testing.T) {
+	retry := ErrTrace{File: "client.go", Function: "(*Client).Do", Line: 42}
+	err := NewBadGateway("upstream down")
+	Stack(err, ErrTrace{File: "main.go", Function: "main", Line: 10})
+	Stack(err, retry)
+	Stack(err, retry)
+	Stack(err, retry)
+	Stack(err, ErrTrace{File: "handler.go", Function: "Handle", Line: 7})
+	Stack(err, retry)
+
+	collapsed := GetStackCollapsed(err)
+	wantCounts := []int{1, 3, 1, 1}
+	if len(collapsed) != len(wantCounts) {
+		t.Fatalf("collapsed stack has %d frames, want %d: %v", len(collapsed), len(wantCounts), collapsed)
+	}
+	for i, want := range wantCounts {
+		if collapsed[i].Count != want {
+			t.Errorf("frame %d count = %d, want %d", i, collapsed[i].Count, want)
+		}
+	}
+	if !sameFrame(collapsed[1], retry) || !sameFrame(collapsed[3], retry) {
+		t.Errorf("collapsed stack = %v, want the retry frames kept apart", collapsed)
+	}
+	if got := len(GetStack(err)); got != 6 {
+		t.Errorf("GetStack has %d frames after collapsing, want the raw 6", got)
+	}
+	if GetStack(err)[1].Count != 0 {
+		t.Error("GetStackCollapsed changed the stored frames")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	notFound := NewNotFound("n")
+	plain := stderrors.New("plain")
+
+	if got := Errors(nil); got != nil {
+		t.Errorf("Errors(nil) = %v, want nil", got)
+	}
+	if got := Errors(notFound); len(got) != 1 || got[0] != notFound {
+		t.Errorf("Errors of a single error = %v, want only that error", got)
+	}
+	got := Errors(stderrors.Join(notFound, nil, plain))
+	if len(got) != 2 || got[0] != notFound || got[1] != plain {
+		t.Errorf("Errors of a joined error = %v, want its two children in order", got)
+	}
+	wrapped := fmt.Errorf("load: %w", notFound)
+	if got := Errors(wrapped); len(got) != 1 || got[0] != wrapped {
+		t.Errorf("Errors of a wrapped error = %v, want it kept whole", got)
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"message only", NewNotFound("user missing"), "user missing"},
+		{"cause only", NewNotFound(stderrors.New("sql: no rows")), "sql: no rows"},
+		{"message and cause", NewNotFound("user missing", stderrors.New("sql: no rows")), "user missing"},
+		{"neither", NewNotFound(), ""},
+		{"plain", stderrors.New("plain"), "plain"},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetMessage(tt.err); got != tt.want {
+				t.Errorf("GetMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	inner := NewNotFound("user missing", stderrors.New("sql: no rows"), ErrTrace{Function: "FindUser", Line: 1})
+	middle := fmt.Errorf("load: %w", inner)
+	outer := NewInternal("request failed", middle, ErrTrace{Function: "Serve", Line: 2})
+
+	flat := Flatten(outer)
+	if got := GetMessage(flat); got != "request failed" {
+		t.Errorf("GetMessage = %q, want the outer message", got)
+	}
+	if got, want := GetCause(flat), "user missing: sql: no rows"; got != want {
+		t.Errorf("GetCause = %q, want %q", got, want)
+	}
+	if !IsInternal(flat) {
+		t.Errorf("Flatten returned %T, want *Internal", flat)
+	}
+	if stack := GetStack(flat); len(stack) != 2 || stack[0].Function != "FindUser" || stack[1].Function != "Serve" {
+		t.Errorf("stack = %v, want the inner frame then the outer one", stack)
+	}
+
+	single := NewConflict("email taken", stderrors.New("duplicate key"))
+	if got := GetCause(Flatten(single)); got != "duplicate key" {
+		t.Errorf("GetCause of a single error = %q, want %q", got, "duplicate key")
+	}
+	alone := NewConflict("email taken")
+	if got := GetCause(Flatten(alone)); got != GetCause(alone) {
+		t.Errorf("GetCause of an error wrapping nothing = %q, want it unchanged", got)
+	}
+}
+
+func TestErrorFWithoutMessage(t *testing.T) {
+	err := NewNotFound(RequestID("r1"), WithReason("expired"), TraceContext("t1", "s1"))
+
+	output := ErrorF(err)
+	for _, want := range []string{"- Request ID: r1", "- Reason: expired", "- Error ID: " + GetErrorID(err), "- Trace ID: t1", "- Span ID: s1", "- Error code: 404"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ErrorF has no %q:\n%s", want, output)
+		}
+	}
+	if got := ErrorF(stderrors.New("plain")); got != "plain" {
+		t.Errorf("ErrorF of a plain error = %q, want %q", got, "plain")
+	}
+}
@@ -1,8 +1,23 @@
 package errors
 
-func parseFields(fields []interface{}) Err {
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
 
-	var err Err
+// parseFields builds an Err from the constructor fields, starting from the
+// type's default code so serialized errors always carry their status.
+// Fields apply in order. A nil error field is ignored and leaves any cause
+// already set untouched; use ClearCause to remove it
+func parseFields(fields []interface{}, code int) Err {
+
+	err := Err{Code: code, Timestamp: time.Now().UTC()}
+	if captureHost {
+		err.Host = currentHost()
+	}
 	for _, field := range fields {
 
 		if e, ok := field.(error); ok {
@@ -25,83 +40,603 @@ func parseFields(fields []interface{}) Err {
 			err.Code = field.(int)
 			continue
 		}
+
+		if opt, ok := field.(Option); ok && opt != nil {
+			opt(&err)
+			continue
+		}
+	}
+	if err.ID == "" {
+		err.ID = newErrorID()
 	}
 	return err
 }
 
+// NewFromStatus returns the typed error matching an HTTP status code, falling
+// back to BadRequest for other 4xx codes and Internal for other 5xx codes. The
+// status is kept as the error's code. Codes that aren't errors, such as 0, 200
+// or 302, give an Internal with code 500; only 202 and 204 keep their types
+func NewFromStatus(code int, fields ...interface{}) error {
+
+	if (code < 400 && code != 202 && code != 204) || code > 599 {
+		code = 500
+	}
+	fields = append(fields, code)
+
+	switch code {
+	case 202:
+		return NewAccepted(fields...)
+	case 204:
+		return NewNoContent(fields...)
+	case 400:
+		return NewBadRequest(fields...)
+	case 401:
+		return NewUnauthenticated(fields...)
+	case 402:
+		return NewPaymentRequired(fields...)
+	case 403:
+		return NewForbidden(fields...)
+	case 404:
+		return NewNotFound(fields...)
+	case 405:
+		return NewMethodNotAllowed(fields...)
+	case 408:
+		return NewRequestTimeout(fields...)
+	case 409:
+		return NewConflict(fields...)
+	case 413:
+		return NewPayloadTooLarge(fields...)
+	case 415:
+		return NewUnsupportedMediaType(fields...)
+	case 416:
+		return NewRangeNotSatisfiable(fields...)
+	case 425:
+		return NewTooEarly(fields...)
+	case 428:
+		return NewPreconditionRequired(fields...)
+	case 429:
+		return NewTooManyRequests(fields...)
+	case 451:
+		return NewUnavailableForLegalReasons(fields...)
+	case 499:
+		return NewClientClosedRequest(fields...)
+	case 502:
+		return NewBadGateway(fields...)
+	}
+
+	if code >= 400 && code < 500 {
+		return NewBadRequest(fields...)
+	}
+	return NewInternal(fields...)
+}
+
+// newFromKind returns the typed error of a kind as reported by GetJSON, or nil
+// for unknown kinds
+func newFromKind(kind string, fields ...interface{}) error {
+
+	switch kind {
+	case "internal":
+		return NewInternal(fields...)
+	case "not_found":
+		return NewNotFound(fields...)
+	case "conflict":
+		return NewConflict(fields...)
+	case "bad_request":
+		return NewBadRequest(fields...)
+	case "unauthorized":
+		return NewUnauthorized(fields...)
+	case "fatal":
+		return NewFatal(fields...)
+	case "no_content":
+		return NewNoContent(fields...)
+	case "payment_required":
+		return NewPaymentRequired(fields...)
+	case "client_closed_request":
+		return NewClientClosedRequest(fields...)
+	case "range_not_satisfiable":
+		return NewRangeNotSatisfiable(fields...)
+	case "unsupported_media_type":
+		return NewUnsupportedMediaType(fields...)
+	case "accepted":
+		return NewAccepted(fields...)
+	case "request_timeout":
+		return NewRequestTimeout(fields...)
+	case "too_many_requests":
+		return NewTooManyRequests(fields...)
+	case "forbidden":
+		return NewForbidden(fields...)
+	case "unauthenticated":
+		return NewUnauthenticated(fields...)
+	case "bad_gateway":
+		return NewBadGateway(fields...)
+	case "payload_too_large":
+		return NewPayloadTooLarge(fields...)
+	case "method_not_allowed":
+		return NewMethodNotAllowed(fields...)
+	case "too_early":
+		return NewTooEarly(fields...)
+	case "precondition_required":
+		return NewPreconditionRequired(fields...)
+	case "unavailable_for_legal_reasons":
+		return NewUnavailableForLegalReasons(fields...)
+	}
+
+	return nil
+}
+
+// formatMsg builds the message of the formatted constructors, truncated to
+// the length set with SetMaxFormatArgLength
+func formatMsg(format string, args ...interface{}) string {
+	return truncate(fmt.Sprintf(format, args...), maxFormatLength)
+}
+
+// newErrorID returns a random UUIDv4 identifying one error instance, or an
+// empty string if the system's random source fails
+func newErrorID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	var id [36]byte
+	hex.Encode(id[0:8], b[0:4])
+	id[8] = '-'
+	hex.Encode(id[9:13], b[4:6])
+	id[13] = '-'
+	hex.Encode(id[14:18], b[6:8])
+	id[18] = '-'
+	hex.Encode(id[19:23], b[8:10])
+	id[23] = '-'
+	hex.Encode(id[24:], b[10:])
+	return string(id[:])
+}
+
+// sameError reports whether target is of the same kind as err with the same
+// code and message. It backs the Is methods once SetValueEquality is enabled
+func sameError(err error, target error) bool {
+	if !valueEquality || kindOf(err) != kindOf(target) {
+		return false
+	}
+	return GetCode(err) == GetCode(target) && extractErr(err).Message == extractErr(target).Message
+}
+
 type BadRequest struct {
 	Err
 }
 func NewBadRequest(fields ...interface{}) *BadRequest {
-	return &BadRequest{Err:parseFields(fields)}
+	return &BadRequest{Err:parseFields(fields, 400)}
+}
+func BadRequestf(format string, args ...interface{}) error {
+	return NewBadRequest(formatMsg(format, args...), callerTrace(3))
 }
 func IsBadRequest(err error) bool {
 	_, ok := err.(*BadRequest)
 	return ok
 }
+func (e *BadRequest) Is(target error) bool {
+	return sameError(e, target)
+}
+
+// NewValidationError returns a BadRequest with the "validation failed" message
+// and the given field details
+func NewValidationError(details ...Detail) error {
+	return NewBadRequest("validation failed", PublicMsg("validation failed"), WithDetails(details...))
+}
 
 type Internal struct{
 	Err
 }
 func NewInternal(fields ...interface{}) *Internal {
-	return &Internal{Err:parseFields(fields)}
+	return &Internal{Err:parseFields(fields, 500)}
+}
+func Internalf(format string, args ...interface{}) error {
+	return NewInternal(formatMsg(format, args...), callerTrace(3))
 }
 func IsInternal(err error) bool {
 	_, ok := err.(*Internal)
 	return ok
 }
+func (e *Internal) Is(target error) bool {
+	return sameError(e, target)
+}
 
 type NotFound struct{
 	Err
 }
 func NewNotFound(fields ...interface{}) *NotFound {
-	return &NotFound{Err:parseFields(fields)}
+	return &NotFound{Err:parseFields(fields, 404)}
+}
+func NotFoundf(format string, args ...interface{}) error {
+	return NewNotFound(formatMsg(format, args...), callerTrace(3))
 }
 func IsNotFound(err error) bool {
 	_, ok := err.(*NotFound)
 	return ok
 }
+func (e *NotFound) Is(target error) bool {
+	return sameError(e, target)
+}
 
 type Conflict struct{
 	Err
 }
 func NewConflict(fields ...interface{}) *Conflict {
-	return &Conflict{Err:parseFields(fields)}
+	return &Conflict{Err:parseFields(fields, 409)}
+}
+func Conflictf(format string, args ...interface{}) error {
+	return NewConflict(formatMsg(format, args...), callerTrace(3))
 }
 func IsConflict(err error) bool {
 	_, ok := err.(*Conflict)
 	return ok
 }
+func (e *Conflict) Is(target error) bool {
+	return sameError(e, target)
+}
 
+// Unauthorized keeps its historical 403 default. New code should use
+// Unauthenticated (401) for missing or invalid credentials and Forbidden (403)
+// for authenticated callers lacking permission. IsForbidden also matches
+// Unauthorized errors with a 403 code
 type Unauthorized struct{
 	Err
 }
 func NewUnauthorized(fields ...interface{}) *Unauthorized {
-	return &Unauthorized{Err:parseFields(fields)}
+	return &Unauthorized{Err:parseFields(fields, 403)}
+}
+func Unauthorizedf(format string, args ...interface{}) error {
+	return NewUnauthorized(formatMsg(format, args...), callerTrace(3))
 }
 func IsUnauthorized(err error) bool {
 	_, ok := err.(*Unauthorized)
 	return ok
 }
+func (e *Unauthorized) Is(target error) bool {
+	return sameError(e, target)
+}
 
 type Fatal struct{
 	Err
 }
 func NewFatal(fields ...interface{}) *Fatal {
-	return &Fatal{Err:parseFields(fields)}
+	return &Fatal{Err:parseFields(fields, 500)}
+}
+func Fatalf(format string, args ...interface{}) error {
+	return NewFatal(formatMsg(format, args...), callerTrace(3))
 }
 func IsFatal(err error) bool {
 	_, ok := err.(*Fatal)
 	return ok
 }
+func (e *Fatal) Is(target error) bool {
+	return sameError(e, target)
+}
 
 type NoContent struct {
 	Err
 }
 func NewNoContent(fields ...interface{}) *NoContent {
-	return &NoContent{Err: parseFields(fields)}
+	return &NoContent{Err: parseFields(fields, 204)}
+}
+func NoContentf(format string, args ...interface{}) error {
+	return NewNoContent(formatMsg(format, args...), callerTrace(3))
 }
 func IsNoContent(err error) bool {
 	_, ok := err.(*NoContent)
 	return ok
+}
+func (e *NoContent) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type PaymentRequired struct {
+	Err
+}
+func NewPaymentRequired(fields ...interface{}) *PaymentRequired {
+	return &PaymentRequired{Err: parseFields(fields, 402)}
+}
+func PaymentRequiredf(format string, args ...interface{}) error {
+	return NewPaymentRequired(formatMsg(format, args...), callerTrace(3))
+}
+func IsPaymentRequired(err error) bool {
+	_, ok := err.(*PaymentRequired)
+	return ok
+}
+func (e *PaymentRequired) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type ClientClosedRequest struct {
+	Err
+}
+func NewClientClosedRequest(fields ...interface{}) *ClientClosedRequest {
+	return &ClientClosedRequest{Err: parseFields(fields, 499)}
+}
+func ClientClosedRequestf(format string, args ...interface{}) error {
+	return NewClientClosedRequest(formatMsg(format, args...), callerTrace(3))
+}
+func IsClientClosedRequest(err error) bool {
+	_, ok := err.(*ClientClosedRequest)
+	return ok
+}
+func (e *ClientClosedRequest) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type RangeNotSatisfiable struct {
+	Err
+}
+func NewRangeNotSatisfiable(fields ...interface{}) *RangeNotSatisfiable {
+	return &RangeNotSatisfiable{Err: parseFields(fields, 416)}
+}
+func RangeNotSatisfiablef(format string, args ...interface{}) error {
+	return NewRangeNotSatisfiable(formatMsg(format, args...), callerTrace(3))
+}
+func IsRangeNotSatisfiable(err error) bool {
+	_, ok := err.(*RangeNotSatisfiable)
+	return ok
+}
+func (e *RangeNotSatisfiable) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type UnsupportedMediaType struct {
+	Err
+}
+func NewUnsupportedMediaType(fields ...interface{}) *UnsupportedMediaType {
+	return &UnsupportedMediaType{Err: parseFields(fields, 415)}
+}
+func UnsupportedMediaTypef(format string, args ...interface{}) error {
+	return NewUnsupportedMediaType(formatMsg(format, args...), callerTrace(3))
+}
+func IsUnsupportedMediaType(err error) bool {
+	_, ok := err.(*UnsupportedMediaType)
+	return ok
+}
+func (e *UnsupportedMediaType) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type Accepted struct {
+	Err
+}
+func NewAccepted(fields ...interface{}) *Accepted {
+	return &Accepted{Err: parseFields(fields, 202)}
+}
+func Acceptedf(format string, args ...interface{}) error {
+	return NewAccepted(formatMsg(format, args...), callerTrace(3))
+}
+func IsAccepted(err error) bool {
+	_, ok := err.(*Accepted)
+	return ok
+}
+func (e *Accepted) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type RequestTimeout struct {
+	Err
+}
+func NewRequestTimeout(fields ...interface{}) *RequestTimeout {
+	return &RequestTimeout{Err: parseFields(fields, 408)}
+}
+func RequestTimeoutf(format string, args ...interface{}) error {
+	return NewRequestTimeout(formatMsg(format, args...), callerTrace(3))
+}
+func IsRequestTimeout(err error) bool {
+	_, ok := err.(*RequestTimeout)
+	return ok
+}
+func (e *RequestTimeout) Is(target error) bool {
+	return sameError(e, target)
+}
+func (e *RequestTimeout) Timeout() bool {
+	return true
+}
+
+type TooManyRequests struct {
+	Err
+}
+func NewTooManyRequests(fields ...interface{}) *TooManyRequests {
+	return &TooManyRequests{Err: parseFields(fields, 429)}
+}
+func TooManyRequestsf(format string, args ...interface{}) error {
+	return NewTooManyRequests(formatMsg(format, args...), callerTrace(3))
+}
+func IsTooManyRequests(err error) bool {
+	_, ok := err.(*TooManyRequests)
+	return ok
+}
+func (e *TooManyRequests) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type Forbidden struct {
+	Err
+}
+func NewForbidden(fields ...interface{}) *Forbidden {
+	return &Forbidden{Err: parseFields(fields, 403)}
+}
+func Forbiddenf(format string, args ...interface{}) error {
+	return NewForbidden(formatMsg(format, args...), callerTrace(3))
+}
+// IsForbidden reports whether err is a Forbidden error. To ease the migration
+// from Unauthorized, it also matches Unauthorized errors that kept their 403
+// code, so callers switching to IsForbidden don't miss legacy errors
+func IsForbidden(err error) bool {
+	if e, ok := err.(*Unauthorized); ok {
+		return e.Code == 403
+	}
+	_, ok := err.(*Forbidden)
+	return ok
+}
+func (e *Forbidden) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type Unauthenticated struct {
+	Err
+}
+func NewUnauthenticated(fields ...interface{}) *Unauthenticated {
+	return &Unauthenticated{Err: parseFields(fields, 401)}
+}
+func Unauthenticatedf(format string, args ...interface{}) error {
+	return NewUnauthenticated(formatMsg(format, args...), callerTrace(3))
+}
+func IsUnauthenticated(err error) bool {
+	_, ok := err.(*Unauthenticated)
+	return ok
+}
+func (e *Unauthenticated) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type BadGateway struct {
+	Err
+}
+func NewBadGateway(fields ...interface{}) *BadGateway {
+	return &BadGateway{Err: parseFields(fields, 502)}
+}
+func BadGatewayf(format string, args ...interface{}) error {
+	return NewBadGateway(formatMsg(format, args...), callerTrace(3))
+}
+func IsBadGateway(err error) bool {
+	_, ok := err.(*BadGateway)
+	return ok
+}
+func (e *BadGateway) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type PayloadTooLarge struct {
+	Err
+}
+func NewPayloadTooLarge(fields ...interface{}) *PayloadTooLarge {
+	return &PayloadTooLarge{Err: parseFields(fields, 413)}
+}
+func PayloadTooLargef(format string, args ...interface{}) error {
+	return NewPayloadTooLarge(formatMsg(format, args...), callerTrace(3))
+}
+func IsPayloadTooLarge(err error) bool {
+	_, ok := err.(*PayloadTooLarge)
+	return ok
+}
+func (e *PayloadTooLarge) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type MethodNotAllowed struct {
+	Err
+}
+func NewMethodNotAllowed(fields ...interface{}) *MethodNotAllowed {
+	return &MethodNotAllowed{Err: parseFields(fields, 405)}
+}
+func MethodNotAllowedf(format string, args ...interface{}) error {
+	return NewMethodNotAllowed(formatMsg(format, args...), callerTrace(3))
+}
+func IsMethodNotAllowed(err error) bool {
+	_, ok := err.(*MethodNotAllowed)
+	return ok
+}
+func (e *MethodNotAllowed) Is(target error) bool {
+	return sameError(e, target)
+}
+
+// Custom is an error category defined by the application with RegisterType
+type Custom struct {
+	Err
+	Kind string `json:"kind"`
+}
+
+var (
+	customTypes   = map[string]int{}
+	customTypesMu sync.RWMutex
+)
+
+// RegisterType defines an error category with its default code and returns
+// its constructor, e.g. NewQuotaExceeded := RegisterType("quota_exceeded", 429).
+// The errors it builds work with the rest of the package like the built-in
+// types; use IsType to detect them. Registering a kind twice, or the kind of a
+// built-in type such as "not_found", panics
+func RegisterType(kind string, code int) func(fields ...interface{}) *Custom {
+	customTypesMu.Lock()
+	defer customTypesMu.Unlock()
+	if _, ok := customTypes[kind]; ok || kind == "" || newFromKind(kind) != nil {
+		panic("errors: invalid or duplicate type " + kind)
+	}
+	customTypes[kind] = code
+
+	return func(fields ...interface{}) *Custom {
+		return &Custom{Err: parseFields(fields, code), Kind: kind}
+	}
+}
+
+// registeredType returns the default code of a kind registered with RegisterType
+func registeredType(kind string) (int, bool) {
+	customTypesMu.RLock()
+	defer customTypesMu.RUnlock()
+	code, ok := customTypes[kind]
+	return code, ok
+}
+
+// IsType reports whether err is a registered error of the given kind
+func IsType(err error, kind string) bool {
+	e, ok := err.(*Custom)
+	return ok && e.Kind == kind
+}
+func (e *Custom) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type TooEarly struct {
+	Err
+}
+func NewTooEarly(fields ...interface{}) *TooEarly {
+	return &TooEarly{Err: parseFields(fields, 425)}
+}
+func TooEarlyf(format string, args ...interface{}) error {
+	return NewTooEarly(formatMsg(format, args...), callerTrace(3))
+}
+func IsTooEarly(err error) bool {
+	_, ok := err.(*TooEarly)
+	return ok
+}
+func (e *TooEarly) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type PreconditionRequired struct {
+	Err
+}
+func NewPreconditionRequired(fields ...interface{}) *PreconditionRequired {
+	return &PreconditionRequired{Err: parseFields(fields, 428)}
+}
+func PreconditionRequiredf(format string, args ...interface{}) error {
+	return NewPreconditionRequired(formatMsg(format, args...), callerTrace(3))
+}
+func IsPreconditionRequired(err error) bool {
+	_, ok := err.(*PreconditionRequired)
+	return ok
+}
+func (e *PreconditionRequired) Is(target error) bool {
+	return sameError(e, target)
+}
+
+type UnavailableForLegalReasons struct {
+	Err
+}
+func NewUnavailableForLegalReasons(fields ...interface{}) *UnavailableForLegalReasons {
+	return &UnavailableForLegalReasons{Err: parseFields(fields, 451)}
+}
+func UnavailableForLegalReasonsf(format string, args ...interface{}) error {
+	return NewUnavailableForLegalReasons(formatMsg(format, args...), callerTrace(3))
+}
+func IsUnavailableForLegalReasons(err error) bool {
+	_, ok := err.(*UnavailableForLegalReasons)
+	return ok
+}
+func (e *UnavailableForLegalReasons) Is(target error) bool {
+	return sameError(e, target)
 }
\ No newline at end of file
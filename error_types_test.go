@@ -0,0 +1,110 @@
+package errors
+
+import (
+	stderrors "errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterType(t *testing.T) {
+	NewQuotaExceeded := RegisterType("quota_exceeded_test", 429)
+
+	err := NewQuotaExceeded("over quota")
+	if !IsType(err, "quota_exceeded_test") || GetCode(err) != 429 {
+		t.Errorf("got kind %q and code %d", kindOf(err), GetCode(err))
+	}
+
+	rebuilt, e := FromJSON(GetJSON(err))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !IsType(rebuilt, "quota_exceeded_test") {
+		t.Errorf("rebuilt %T with kind %q", rebuilt, kindOf(rebuilt))
+	}
+}
+
+func TestRegisterTypeRejectsBuiltInKinds(t *testing.T) {
+	for _, kind := range []string{"not_found", "internal", "unauthorized", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterType(%q) didn't panic", kind)
+				}
+			}()
+			RegisterType(kind, 404)
+		}()
+	}
+}
+
+func TestIsForbiddenMatchesLegacyUnauthorized(t *testing.T) {
+	if !IsForbidden(NewForbidden()) {
+		t.Error("IsForbidden(Forbidden) = false")
+	}
+	if !IsForbidden(NewUnauthorized()) {
+		t.Error("IsForbidden doesn't match a 403 Unauthorized")
+	}
+	if IsForbidden(NewUnauthorized(401)) {
+		t.Error("IsForbidden matches an Unauthorized with a 401 code")
+	}
+	if IsForbidden(NewUnauthenticated()) {
+		t.Error("IsForbidden matches Unauthenticated")
+	}
+}
+
+func TestNewFromStatusNonErrorCodes(t *testing.T) {
+	for _, code := range []int{0, 100, 200, 301, 302, 304, 600} {
+		err := NewFromStatus(code, "x")
+		if !IsInternal(err) || GetCode(err) != 500 || IsSuccess(err) {
+			t.Errorf("NewFromStatus(%d) = %T with code %d, want an Internal with 500", code, err, GetCode(err))
+		}
+	}
+	if err := EnsureTyped(stderrors.New("redirected"), 302); !IsInternal(err) || GetCode(err) != 500 {
+		t.Errorf("EnsureTyped(err, 302) = %T with code %d, want an Internal with 500", err, GetCode(err))
+	}
+}
+
+func TestNewFromStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		kind string
+	}{
+		{202, "accepted"},
+		{204, "no_content"},
+		{404, "not_found"},
+		{418, "bad_request"},
+		{503, "internal"},
+	}
+	for _, tt := range tests {
+		err := NewFromStatus(tt.code)
+		if kindOf(err) != tt.kind || GetCode(err) != tt.code {
+			t.Errorf("NewFromStatus(%d) = %q with code %d", tt.code, kindOf(err), GetCode(err))
+		}
+	}
+}
+
+func TestErrorID(t *testing.T) {
+	id := GetErrorID(NewNotFound("x"))
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[14] != '4' || id[18] != '-' || id[23] != '-' || !strings.ContainsAny(id[19:20], "89ab") {
+		t.Errorf("error ID %q isn't a UUIDv4", id)
+	}
+	if other := GetErrorID(NewNotFound("x")); other == id {
+		t.Errorf("two errors share the ID %q", id)
+	}
+	if got := GetErrorID(NewNotFound("x", ErrorID("id-1"))); got != "id-1" {
+		t.Errorf("GetErrorID = %q, want the ID set with ErrorID", got)
+	}
+}
+
+func BenchmarkNewNotFound(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewNotFound("x")
+	}
+}
+
+func BenchmarkNewNotFoundWithErrorID(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewNotFound("x", ErrorID("id-1"))
+	}
+}
@@ -0,0 +1,18 @@
+// Package errorstest provides test helpers for errors built with
+// github.com/jurado-dev/errors
+package errorstest
+
+import (
+	"testing"
+
+	"github.com/jurado-dev/errors"
+)
+
+// RequireNil fails the test immediately when err is not nil, reporting the
+// full error information, including the stack
+func RequireNil(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", errors.ErrorF(err))
+	}
+}
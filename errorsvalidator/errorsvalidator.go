@@ -0,0 +1,27 @@
+// Package errorsvalidator converts github.com/go-playground/validator errors
+// into errors built with github.com/jurado-dev/errors. It is a separate module
+// so the validator dependency is only pulled in by those who import it
+package errorsvalidator
+
+import (
+	stderrors "errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jurado-dev/errors"
+)
+
+// FromValidation converts validator.ValidationErrors into a BadRequest with a
+// Detail per failed field. Other errors are returned unchanged
+func FromValidation(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !stderrors.As(err, &validationErrors) {
+		return err
+	}
+
+	details := make([]errors.Detail, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		details = append(details, errors.Detail{Field: fieldErr.Field(), Reason: fieldErr.Tag()})
+	}
+
+	return errors.AppendCause(errors.NewValidationError(details...), err)
+}
@@ -0,0 +1,40 @@
+package errorsvalidator
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jurado-dev/errors"
+)
+
+type signup struct {
+	Email string `validate:"required,email"`
+	Age   int    `validate:"gte=0"`
+}
+
+func TestFromValidation(t *testing.T) {
+	err := FromValidation(validator.New().Struct(signup{Email: "nope", Age: -1}))
+
+	if !errors.IsBadRequest(err) {
+		t.Fatalf("FromValidation = %T, want *BadRequest", err)
+	}
+	details := errors.GetDetails(err)
+	if len(details) != 2 || details[0] != (errors.Detail{Field: "Email", Reason: "email"}) || details[1] != (errors.Detail{Field: "Age", Reason: "gte"}) {
+		t.Errorf("details = %v", details)
+	}
+	var validationErrors validator.ValidationErrors
+	if !stderrors.As(err, &validationErrors) {
+		t.Error("the validation errors aren't wrapped")
+	}
+}
+
+func TestFromValidationOtherErrors(t *testing.T) {
+	other := stderrors.New("other")
+	if got := FromValidation(other); got != other {
+		t.Errorf("FromValidation = %v, want the error unchanged", got)
+	}
+	if FromValidation(nil) != nil {
+		t.Error("FromValidation(nil) != nil")
+	}
+}
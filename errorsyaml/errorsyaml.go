@@ -0,0 +1,36 @@
+// Package errorsyaml renders errors built with github.com/jurado-dev/errors as
+// YAML. It is a separate module so the YAML dependency is only pulled in by
+// those who import it
+package errorsyaml
+
+import (
+	stderrors "errors"
+
+	"github.com/jurado-dev/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// GetYAML returns the error as YAML, with the same fields as errors.GetJSON
+func GetYAML(err error) ([]byte, error) {
+	encoded := errors.GetJSON(err)
+	if encoded == nil {
+		return nil, stderrors.New("errorsyaml: the error can't be encoded")
+	}
+
+	// JSON is valid YAML, so decoding it into a node keeps the field order
+	var node yaml.Node
+	if e := yaml.Unmarshal(encoded, &node); e != nil {
+		return nil, e
+	}
+	resetStyle(&node)
+
+	return yaml.Marshal(&node)
+}
+
+// resetStyle drops the flow and quoting styles taken from the JSON input
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
@@ -0,0 +1,29 @@
+package errorsyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jurado-dev/errors"
+)
+
+func TestGetYAML(t *testing.T) {
+	out, err := GetYAML(errors.NewNotFound("user missing", errors.ErrorID("id-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"kind: not_found", "code: 404", "message: user missing", "error_id: id-1"} {
+		if !strings.Contains(string(out), line+"\n") {
+			t.Errorf("YAML has no %q line:\n%s", line, out)
+		}
+	}
+	if strings.Index(string(out), "kind:") > strings.Index(string(out), "message:") {
+		t.Errorf("YAML doesn't keep the JSON field order:\n%s", out)
+	}
+}
+
+func TestGetYAMLUnencodable(t *testing.T) {
+	if _, err := GetYAML(errors.NewInternal("x", errors.Field("ch", make(chan int)))); err == nil {
+		t.Error("GetYAML encoded a channel field")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/jurado-dev/errors/errorsyaml
+
+go 1.20
+
+require (
+	github.com/jurado-dev/errors v0.0.0-00010101000000-000000000000
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/jurado-dev/errors => ../
@@ -0,0 +1,3 @@
+module github.com/jurado-dev/errors
+
+go 1.20